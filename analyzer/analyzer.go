@@ -0,0 +1,200 @@
+// Package analyzer provides go-fixname as an analysis.Analyzer, so that
+// it can be run by go vet -vettool, multichecker or gopls.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/config"
+	"github.com/knzm/go-fixname/lint"
+	"github.com/knzm/go-fixname/rename"
+)
+
+const Doc = `check that names follow golint's naming conventions
+
+The fixname analyzer reports names that break golint's conventions:
+names that use underscores or inconsistently capitalized initialisms,
+package names and import aliases that are not all lower case, exported
+names that stutter with the package name, as in http.HTTPServer,
+receivers named this or self or named differently across the methods of
+a type, and error variables created by errors.New or fmt.Errorf and
+error types that are not named ErrFoo and FooError.
+
+Each diagnostic carries a suggested fix that renames the declaration and
+every reference to it within the package; references from other packages
+are not updated. No fix is suggested for a package name, as renaming it
+involves its importers, nor if the new name would conflict with an
+existing one.`
+
+var Analyzer = &analysis.Analyzer{
+	Name: "fixname",
+	Doc:  Doc,
+	URL:  "https://github.com/knzm/go-fixname",
+	Run:  run,
+}
+
+var (
+	configFile string
+	configOnce sync.Once
+	configErr  error
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&configFile, "config", "", "configuration file (default: "+config.FileName+" in the current directory or above)")
+}
+
+// loadConfig applies the configuration file given by -config, or else
+// the one found from the current directory upward, if any, once.
+func loadConfig() error {
+	configOnce.Do(func() {
+		var c *config.Config
+		if c, _, configErr = config.LoadDefault(configFile); configErr == nil && c != nil {
+			configErr = c.Apply()
+		}
+	})
+	return configErr
+}
+
+type candidate struct {
+	id    *ast.Ident
+	thing interface{}
+	obj   types.Object
+	spec  *lint.Spec
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if err := loadConfig(); err != nil {
+		return nil, err
+	}
+
+	var candidates []candidate
+	var packageReported bool
+	receivers := lint.NewReceivers(pass.Pkg.Path())
+	receiverThings := make(map[*ast.Ident]lint.ReceiverObj)
+	defs := rename.NewDefIndex(pass.TypesInfo)
+	for _, f := range pass.Files {
+		lint.WalkNames(pass.Fset, f, pass.TypesInfo, func(id *ast.Ident, thing interface{}) {
+			if recv, ok := thing.(lint.ReceiverObj); ok {
+				// checked once all the methods have been walked
+				receivers.Add(id, recv)
+				receiverThings[id] = recv
+				return
+			}
+			spec := lint.CheckThing(pass.Pkg.Path(), f, pass.TypesInfo, id, thing)
+			if spec == nil {
+				return
+			}
+			if _, ok := thing.(lint.PackageObj); ok {
+				// Renaming a package involves its importers, which a
+				// pass cannot edit, so there is no suggested fix. An
+				// external test package follows the package it tests.
+				if !packageReported && !strings.HasSuffix(pass.Pkg.Path(), "_test") {
+					packageReported = true
+					pass.Report(analysis.Diagnostic{
+						Pos:      id.Pos(),
+						End:      id.End(),
+						Category: spec.Category.String(),
+						Message:  fmt.Sprintf("%s %s should be %s", thing, id.Name, spec.To),
+					})
+				}
+				return
+			}
+			if obj := defs.DefOf(id); obj != nil {
+				candidates = append(candidates, candidate{id, thing, obj, spec})
+			}
+		})
+	}
+	for _, spec := range receivers.Check() {
+		if obj := pass.TypesInfo.Defs[spec.Id]; obj != nil {
+			candidates = append(candidates, candidate{spec.Id, receiverThings[spec.Id], obj, spec})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// Use the renamer's conflict checker to find the renames that
+	// cannot be suggested.
+	renamer := rename.New(pass.Fset, []*packages.Package{{
+		ID:        pass.Pkg.Path(),
+		Name:      pass.Pkg.Name(),
+		PkgPath:   pass.Pkg.Path(),
+		Fset:      pass.Fset,
+		Syntax:    pass.Files,
+		Types:     pass.Pkg,
+		TypesInfo: pass.TypesInfo,
+	}})
+	for _, c := range candidates {
+		renamer.Rename(c.obj, *c.spec)
+	}
+	// Objects are keyed by declaration, since the symbolic variable of
+	// a type switch declares an implicit object in each case clause.
+	type declKey struct {
+		pos  token.Pos
+		name string
+	}
+	keyOf := func(obj types.Object) declKey {
+		return declKey{obj.Pos(), obj.Name()}
+	}
+
+	conflicts := make(map[declKey]bool)
+	for _, c := range renamer.Check() {
+		conflicts[keyOf(c.Obj)] = true
+	}
+
+	refs := make(map[declKey][]*ast.Ident)
+	for _, m := range []map[*ast.Ident]types.Object{pass.TypesInfo.Defs, pass.TypesInfo.Uses} {
+		for id, obj := range m {
+			if obj != nil && obj.Pos().IsValid() {
+				refs[keyOf(obj)] = append(refs[keyOf(obj)], id)
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		diag := analysis.Diagnostic{
+			Pos:      c.id.Pos(),
+			End:      c.id.End(),
+			Category: c.spec.Category.String(),
+			Message:  fmt.Sprintf("%s %s should be %s", c.thing, c.id.Name, c.spec.To),
+		}
+		if !conflicts[keyOf(c.obj)] {
+			fix := analysis.SuggestedFix{
+				Message: fmt.Sprintf("Rename %s to %s", c.id.Name, c.spec.To),
+			}
+			// Methods linked by interfaces, and a type and the fields
+			// embedding it, are renamed together. An embedded field
+			// shares its identifier with the type, and the symbolic
+			// variable of a type switch is in neither Defs nor Uses.
+			seen := make(map[*ast.Ident]bool)
+			for _, obj := range renamer.Group(c.obj) {
+				ids := refs[keyOf(obj)]
+				if keyOf(obj) == keyOf(c.obj) {
+					ids = append([]*ast.Ident{c.id}, ids...)
+				}
+				for _, id := range ids {
+					if seen[id] {
+						continue
+					}
+					seen[id] = true
+					fix.TextEdits = append(fix.TextEdits, analysis.TextEdit{
+						Pos:     id.Pos(),
+						End:     id.End(),
+						NewText: []byte(c.spec.To),
+					})
+				}
+			}
+			diag.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+		pass.Report(diag)
+	}
+	return nil, nil
+}
@@ -0,0 +1,65 @@
+package a
+
+import (
+	"errors"
+	str_conv "strconv" // want `import alias str_conv should be strconv`
+)
+
+var user_id = 1 // want `var user_id should be userID`
+
+const MAX_SIZE = 10 // want `const MAX_SIZE should be MaxSize`
+
+type Http_Client struct { // want `type Http_Client should be HTTPClient`
+	Base_Url string // want `struct field Base_Url should be BaseURL`
+}
+
+func (c *Http_Client) Get_Url() string { // want `method Get_Url should be GetURL`
+	return c.Base_Url
+}
+
+func f() int {
+	userID := 2
+	return user_id + userID + MAX_SIZE
+}
+
+type urlGetter interface {
+	Get_Url() string //fixname:ignore
+}
+
+var _ urlGetter = (*Http_Client)(nil)
+
+func (this *Http_Client) Reset() { // want `receiver this should be c`
+	this.Base_Url = ""
+}
+
+func (client *Http_Client) Has(c string) bool { // the receiver keeps its name, as c is a parameter
+	return client.Base_Url == c
+}
+
+func kind(x interface{}) string {
+	switch x_val := x.(type) { // want `type switch var x_val should be xVal`
+	case int:
+		_ = x_val
+		return "int"
+	case string:
+		return x_val
+	}
+	return ""
+}
+
+func itoa(i int) string {
+	return str_conv.Itoa(i)
+}
+
+var NotFound = errors.New("not found") // want `var NotFound should be ErrNotFound`
+
+type syntaxErr struct{} // want `type syntaxErr should be syntaxError`
+
+func (e *syntaxErr) Error() string { return "syntax" }
+
+func parse(s string) error {
+	if s == "" {
+		return NotFound
+	}
+	return &syntaxErr{}
+}
@@ -0,0 +1,3 @@
+package my_pkg // want `package my_pkg should be mypkg`
+
+var userID = 1
@@ -0,0 +1,3 @@
+package my_pkg
+
+var maxSize = 10
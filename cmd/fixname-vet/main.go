@@ -0,0 +1,15 @@
+// The fixname-vet command runs the fixname analyzer standalone or as a
+// vet tool:
+//
+//	go vet -vettool=$(which fixname-vet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/knzm/go-fixname/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
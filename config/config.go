@@ -0,0 +1,219 @@
+// Package config reads the per-project configuration of go-fixname.
+//
+// The configuration lives in a YAML file named .fixname.yaml, which is
+// looked up from the working directory upward:
+//
+//	initialisms:
+//	  add: [AWS, GRPC, K8S, SKU, OAuth]
+//	  remove: [VM]
+//
+// An override list replaces golint's default initialisms altogether;
+// add and remove are applied after it.
+//
+// Names that must be kept as they are, for example because an external
+// protocol mandates them, are listed as exceptions:
+//
+//	exceptions:
+//	  - name: X_Forwarded_For
+//	  - name: Wire_*
+//	    package: example.com/proto/...
+//	  - regexp: ^C_
+//
+// A name is matched as a pattern for path.Match, and a regexp is
+// unanchored. An exception without a package applies to every package.
+//
+// The receivers of the methods of a type are named alike, after the
+// most common name among them unless one is configured for the type:
+//
+//	receivers:
+//	  - type: Server
+//	    name: srv
+//	  - type: '*'
+//	    name: x
+//	    package: example.com/gen/...
+//
+// A type is matched as a pattern for path.Match, and the first matching
+// entry wins.
+package config
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// FileName is the name of the configuration file.
+const FileName = ".fixname.yaml"
+
+type Config struct {
+	Initialisms Initialisms `yaml:"initialisms"`
+	Exceptions  []Exception `yaml:"exceptions"`
+	Receivers   []Receiver  `yaml:"receivers"`
+}
+
+type Initialisms struct {
+	Add      []string `yaml:"add"`
+	Remove   []string `yaml:"remove"`
+	Override []string `yaml:"override"`
+}
+
+type Exception struct {
+	Name    string `yaml:"name"`
+	Regexp  string `yaml:"regexp"`
+	Package string `yaml:"package"`
+}
+
+type Receiver struct {
+	Type    string `yaml:"type"`
+	Name    string `yaml:"name"`
+	Package string `yaml:"package"`
+}
+
+// Find returns the path of the configuration file in dir or its
+// nearest ancestor that has one, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		filename := filepath.Join(dir, FileName)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadDefault reads the configuration file filename, or else the one
+// Find finds from the working directory, returning its path. It returns
+// a nil Config if filename is "" and there is none.
+func LoadDefault(filename string) (*Config, string, error) {
+	if filename == "" {
+		var err error
+		filename, err = Find(".")
+		if err != nil || filename == "" {
+			return nil, "", err
+		}
+	}
+	c, err := Load(filename)
+	return c, filename, err
+}
+
+// Load reads the configuration file.
+func Load(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filename)
+}
+
+// Parse parses the content of a configuration file; filename is used
+// in error messages.
+func Parse(data []byte, filename string) (*Config, error) {
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if _, err := c.nameExceptions(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if _, err := c.receiverNames(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return &c, nil
+}
+
+// List returns the initialisms resulting from applying the
+// configuration to defaults, which maps the upper-case form of each
+// initialism to its form in names, in sorted order.
+func (i Initialisms) List(defaults map[string]string) []string {
+	m := make(map[string]string)
+	if i.Override != nil {
+		for _, s := range i.Override {
+			m[strings.ToUpper(s)] = s
+		}
+	} else {
+		for k, v := range defaults {
+			m[k] = v
+		}
+	}
+	for _, s := range i.Add {
+		m[strings.ToUpper(s)] = s
+	}
+	for _, s := range i.Remove {
+		delete(m, strings.ToUpper(s))
+	}
+
+	list := make([]string, 0, len(m))
+	for _, s := range m {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (c *Config) nameExceptions() ([]lint.NameException, error) {
+	var excs []lint.NameException
+	for i, e := range c.Exceptions {
+		exc := lint.NameException{Name: e.Name, Package: e.Package}
+		switch {
+		case e.Regexp != "":
+			re, err := regexp.Compile(e.Regexp)
+			if err != nil {
+				return nil, fmt.Errorf("exceptions[%d]: %v", i, err)
+			}
+			exc.Regexp = re
+		case e.Name == "":
+			return nil, fmt.Errorf("exceptions[%d]: either name or regexp is required", i)
+		}
+		excs = append(excs, exc)
+	}
+	return excs, nil
+}
+
+func (c *Config) receiverNames() ([]lint.ReceiverName, error) {
+	var names []lint.ReceiverName
+	for i, r := range c.Receivers {
+		switch {
+		case r.Type == "":
+			return nil, fmt.Errorf("receivers[%d]: type is required", i)
+		case !token.IsIdentifier(r.Name):
+			return nil, fmt.Errorf("receivers[%d]: invalid name %q", i, r.Name)
+		}
+		names = append(names, lint.ReceiverName{Package: r.Package, Type: r.Type, Name: r.Name})
+	}
+	return names, nil
+}
+
+// Apply makes the lint package use the configuration.
+func (c *Config) Apply() error {
+	excs, err := c.nameExceptions()
+	if err != nil {
+		return err
+	}
+	names, err := c.receiverNames()
+	if err != nil {
+		return err
+	}
+	lint.SetInitialisms(c.Initialisms.List(lint.DefaultInitialisms()))
+	lint.SetNameExceptions(excs)
+	lint.SetReceiverNames(names)
+	return nil
+}
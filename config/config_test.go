@@ -0,0 +1,183 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInitialismsList(t *testing.T) {
+	defaults := map[string]string{"HTTP": "HTTP", "ID": "ID", "VM": "VM"}
+	testData := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			src:      ``,
+			expected: []string{"HTTP", "ID", "VM"},
+		},
+		{
+			name: "add and remove",
+			src: `
+initialisms:
+  add: [AWS, OAuth]
+  remove: [vm]
+`,
+			expected: []string{"AWS", "HTTP", "ID", "OAuth"},
+		},
+		{
+			name: "override",
+			src: `
+initialisms:
+  override: [GRPC, ID]
+  add: [SKU]
+`,
+			expected: []string{"GRPC", "ID", "SKU"},
+		},
+	}
+
+	for _, tt := range testData {
+		c, err := Parse([]byte(tt.src), FileName)
+		if err != nil {
+			t.Errorf("Test: %s, unexpected error: %v", tt.name, err)
+			continue
+		}
+		actual := c.Initialisms.List(defaults)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root, err := ioutil.TempDir("", "fixname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if filename, err := Find(sub); err != nil || filename != "" {
+		t.Errorf("expected no config file, got: %q, %v", filename, err)
+	}
+
+	expected := filepath.Join(root, "a", FileName)
+	if err := ioutil.WriteFile(expected, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if filename, err := Find(sub); err != nil || filename != expected {
+		t.Errorf("expected: %q, got: %q, %v", expected, filename, err)
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	root, err := ioutil.TempDir("", "fixname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	sub := filepath.Join(root, "a")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join(root, FileName)
+	if err := ioutil.WriteFile(expected, []byte("initialisms:\n  add: [SKU]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	c, filename, err := LoadDefault("")
+	if err != nil || c == nil || filename != expected {
+		t.Fatalf("expected: %q, got: %v, %q, %v", expected, c, filename, err)
+	}
+	if len(c.Initialisms.Add) != 1 || c.Initialisms.Add[0] != "SKU" {
+		t.Errorf("unexpected initialisms: %+v", c.Initialisms)
+	}
+
+	// A file given explicitly wins.
+	given := filepath.Join(sub, "fixname.yaml")
+	if err := ioutil.WriteFile(given, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, filename, err := LoadDefault(given); err != nil || filename != given {
+		t.Errorf("expected: %q, got: %q, %v", given, filename, err)
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	c, err := Parse([]byte(`
+exceptions:
+  - name: X_Forwarded_For
+  - regexp: ^C_
+    package: example.com/cgo/...
+`), FileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excs, err := c.nameExceptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(excs) != 2 || excs[0].Name != "X_Forwarded_For" || excs[1].Regexp.String() != "^C_" || excs[1].Package != "example.com/cgo/..." {
+		t.Errorf("unexpected exceptions: %+v", excs)
+	}
+
+	for _, src := range []string{
+		"exceptions:\n  - package: example.com/a\n",
+		"exceptions:\n  - regexp: \"(\"\n",
+	} {
+		if _, err := Parse([]byte(src), FileName); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestReceivers(t *testing.T) {
+	c, err := Parse([]byte(`
+receivers:
+  - type: Server
+    name: srv
+  - type: '*'
+    name: x
+    package: example.com/gen/...
+`), FileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := c.receiverNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0].Type != "Server" || names[0].Name != "srv" || names[1].Package != "example.com/gen/..." {
+		t.Errorf("unexpected receivers: %+v", names)
+	}
+
+	for _, src := range []string{
+		"receivers:\n  - name: srv\n",
+		"receivers:\n  - type: Server\n",
+		"receivers:\n  - type: Server\n    name: my-srv\n",
+	} {
+		if _, err := Parse([]byte(src), FileName); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
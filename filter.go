@@ -13,6 +13,9 @@ const (
 	AllCategory = categoryBits(0)
 	Caps        = categoryBits(1 << iota)
 	Underscore
+	Stutter
+	Recv
+	ErrorName
 )
 
 const (
@@ -22,6 +25,12 @@ const (
 	Type
 	StructField
 	Func
+	TypeParam
+	Receiver
+	Label
+	TypeSwitchVar
+	SelectVar
+	FuncLit
 )
 
 type Filter struct {
@@ -42,6 +51,15 @@ func (f Filter) byCategory(category lint.Category) bool {
 	case lint.Underscore:
 		// underscore
 		return f.category&Underscore != 0
+	case lint.Stutter:
+		// stutter
+		return f.category&Stutter != 0
+	case lint.Receiver:
+		// recv
+		return f.category&Recv != 0
+	case lint.ErrorName:
+		// error
+		return f.category&ErrorName != 0
 	default:
 		return false
 	}
@@ -52,6 +70,34 @@ func (f Filter) byThing(thing interface{}) bool {
 		return true
 	}
 
+	// receiver, type switch var, select var and the parameters and
+	// results of function literals, which var also matches
+	var bit thingBits
+	switch v := thing.(type) {
+	case lint.ReceiverObj:
+		bit = Receiver
+	case lint.TypeSwitchVarObj:
+		bit = TypeSwitchVar
+	case lint.SelectVarObj:
+		bit = SelectVar
+	case lint.ParameterVarObj:
+		if v.OfFuncLit() {
+			bit = FuncLit
+		}
+	case lint.ResultVarObj:
+		if v.OfFuncLit() {
+			bit = FuncLit
+		}
+	}
+	if f.thing&bit != 0 {
+		return true
+	}
+
+	// label
+	if _, ok := thing.(lint.LabelObj); ok {
+		return f.thing&Label != 0
+	}
+
 	// const
 	if _, ok := thing.(lint.ConstObj); ok {
 		return f.thing&Const != 0
@@ -77,6 +123,11 @@ func (f Filter) byThing(thing interface{}) bool {
 		return f.thing&Func != 0
 	}
 
+	// type parameter
+	if _, ok := thing.(lint.TypeParamObj); ok {
+		return f.thing&TypeParam != 0
+	}
+
 	return false
 }
 
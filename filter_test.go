@@ -89,6 +89,39 @@ func TestFilterCategory(t *testing.T) {
 			filter:   Filter{category: Caps | Underscore},
 			expected: false,
 		},
+		{
+			name:     "Caps|Underscore should not match Stutter",
+			category: lint.Stutter,
+			filter:   Filter{category: Caps | Underscore},
+			expected: false,
+		},
+		// Stutter
+		{
+			name:     "Stutter should match Stutter",
+			category: lint.Stutter,
+			filter:   Filter{category: Stutter},
+			expected: true,
+		},
+		// Recv
+		{
+			name:     "Recv should match Receiver",
+			category: lint.Receiver,
+			filter:   Filter{category: Recv},
+			expected: true,
+		},
+		{
+			name:     "Recv should not match Underscore",
+			category: lint.Underscore,
+			filter:   Filter{category: Recv},
+			expected: false,
+		},
+		// ErrorName
+		{
+			name:     "ErrorName should match ErrorName",
+			category: lint.ErrorName,
+			filter:   Filter{category: ErrorName},
+			expected: true,
+		},
 	}
 
 	for _, tt := range testData {
@@ -196,6 +229,89 @@ func TestFilterThing(t *testing.T) {
 			filter:   Filter{thing: Func},
 			expected: true,
 		},
+		// TypeParam
+		{
+			name:     "TypeParam should match type parameter",
+			thing:    lint.TypeParamObj{},
+			filter:   Filter{thing: TypeParam},
+			expected: true,
+		},
+		{
+			name:     "Type should not match type parameter",
+			thing:    lint.TypeParamObj{},
+			filter:   Filter{thing: Type},
+			expected: false,
+		},
+		// Receiver
+		{
+			name:     "Receiver should match receiver",
+			thing:    lint.ReceiverObj{},
+			filter:   Filter{thing: Receiver},
+			expected: true,
+		},
+		{
+			name:     "Var should match receiver",
+			thing:    lint.ReceiverObj{},
+			filter:   Filter{thing: Var},
+			expected: true,
+		},
+		{
+			name:     "Receiver should not match var",
+			thing:    lint.VarObj{},
+			filter:   Filter{thing: Receiver},
+			expected: false,
+		},
+		// Label
+		{
+			name:     "Label should match label",
+			thing:    lint.LabelObj{},
+			filter:   Filter{thing: Label},
+			expected: true,
+		},
+		{
+			name:     "Var should not match label",
+			thing:    lint.LabelObj{},
+			filter:   Filter{thing: Var},
+			expected: false,
+		},
+		// TypeSwitchVar and SelectVar
+		{
+			name:     "TypeSwitchVar should match type switch var",
+			thing:    lint.TypeSwitchVarObj{},
+			filter:   Filter{thing: TypeSwitchVar},
+			expected: true,
+		},
+		{
+			name:     "SelectVar should not match type switch var",
+			thing:    lint.TypeSwitchVarObj{},
+			filter:   Filter{thing: SelectVar},
+			expected: false,
+		},
+		{
+			name:     "SelectVar should match select var",
+			thing:    lint.SelectVarObj{},
+			filter:   Filter{thing: SelectVar},
+			expected: true,
+		},
+		// FuncLit
+		{
+			name:     "FuncLit should match func literal parameter var",
+			thing:    lint.NewFuncLitParameterVarObj(),
+			filter:   Filter{thing: FuncLit},
+			expected: true,
+		},
+		{
+			name:     "FuncLit should match func literal result var",
+			thing:    lint.NewFuncLitResultVarObj(),
+			filter:   Filter{thing: FuncLit},
+			expected: true,
+		},
+		{
+			name:     "FuncLit should not match function parameter var",
+			thing:    lint.NewFunctionParameterVarObj(),
+			filter:   Filter{thing: FuncLit},
+			expected: false,
+		},
 	}
 
 	for _, tt := range testData {
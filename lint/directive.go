@@ -0,0 +1,150 @@
+package lint
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// A directive suppresses the names on a line. An empty category list
+// suppresses every name.
+type directive struct {
+	categories []string
+}
+
+func (d *directive) suppresses(f *ast.File, info *types.Info, id *ast.Ident, thing interface{}) bool {
+	if len(d.categories) == 0 {
+		return true
+	}
+	if _, ok := thing.(ReceiverObj); ok && d.has(Receiver) {
+		// whether a receiver is renamed for consistency depends on the
+		// other methods of its type
+		return true
+	}
+	spec := checkThing(f, info, id, thing)
+	return spec != nil && d.has(spec.Category)
+}
+
+// has reports whether d suppresses the names in category.
+func (d *directive) has(category Category) bool {
+	if len(d.categories) == 0 {
+		return true
+	}
+	for _, c := range d.categories {
+		if c == category.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// Directives are the directives of a file, for renames that WalkNames
+// does not visit, such as those of the methods renamed together.
+type Directives struct {
+	fset  *token.FileSet
+	lines map[int]*directive
+	skip  bool
+}
+
+// ParseDirectives returns the directives of astfile.
+func ParseDirectives(fset *token.FileSet, astfile *ast.File) *Directives {
+	lines, skip := directives(fset, astfile)
+	return &Directives{fset: fset, lines: lines, skip: skip}
+}
+
+// Suppresses reports whether a directive suppresses renaming the name
+// declared at pos in category.
+func (ds *Directives) Suppresses(pos token.Pos, category Category) bool {
+	if ds.skip {
+		return true
+	}
+	d := ds.lines[ds.fset.Position(pos).Line]
+	return d != nil && d.has(category)
+}
+
+// parseDirective parses a comment of one of the forms
+//
+//	//fixname:ignore [category...]
+//	//nolint
+//	//nolint:linter[,linter...]
+//
+// optionally followed by "// explanation". It also reports whether the
+// comment is the file-level directive //fixname:ignore-file.
+func parseDirective(text string) (d *directive, file bool) {
+	if !strings.HasPrefix(text, "//") {
+		return nil, false
+	}
+	text = text[len("//"):]
+	if i := strings.Index(text, "//"); i >= 0 {
+		text = text[:i]
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	switch verb := fields[0]; {
+	case verb == "fixname:ignore-file":
+		return nil, true
+	case verb == "fixname:ignore":
+		return &directive{categories: fields[1:]}, false
+	case verb == "nolint":
+		return &directive{}, false
+	case strings.HasPrefix(verb, "nolint:"):
+		for _, linter := range strings.Split(verb[len("nolint:"):], ",") {
+			if linter == "fixname" || linter == "all" {
+				return &directive{}, false
+			}
+		}
+	}
+	return nil, false
+}
+
+// directives returns the directives of astfile by the line they apply
+// to, or nil and true if the whole file is to be skipped. A directive
+// applies to its own line if it trails code, and otherwise to the line
+// following its comment group.
+func directives(fset *token.FileSet, astfile *ast.File) (map[int]*directive, bool) {
+	var lines map[int]*directive
+	var ends map[int]int // line -> smallest column at which a node ends
+	for _, cg := range astfile.Comments {
+		for _, c := range cg.List {
+			d, file := parseDirective(c.Text)
+			if file {
+				return nil, true
+			}
+			if d == nil {
+				continue
+			}
+			if ends == nil {
+				ends = nodeEnds(fset, astfile)
+			}
+			pos := fset.Position(c.Pos())
+			line := pos.Line
+			if col, ok := ends[line]; !ok || col > pos.Column {
+				line = fset.Position(cg.End()).Line + 1
+			}
+			if lines == nil {
+				lines = make(map[int]*directive)
+			}
+			lines[line] = d
+		}
+	}
+	return lines, false
+}
+
+func nodeEnds(fset *token.FileSet, astfile *ast.File) map[int]int {
+	ends := make(map[int]int)
+	ast.Inspect(astfile, func(node ast.Node) bool {
+		switch node.(type) {
+		case nil, *ast.Comment, *ast.CommentGroup:
+			return false
+		}
+		pos := fset.Position(node.End())
+		if col, ok := ends[pos.Line]; !ok || pos.Column < col {
+			ends[pos.Line] = pos.Column
+		}
+		return true
+	})
+	return ends
+}
@@ -0,0 +1,149 @@
+package lint
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestWalkNamesDirectives(t *testing.T) {
+	testData := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "same line",
+			src: `package p
+var foo_bar int //fixname:ignore
+var foo_baz int
+`,
+			expected: []string{"p", "foo_baz"},
+		},
+		{
+			name: "line above",
+			src: `package p
+
+//fixname:ignore // mirrors the wire format
+const X_Forwarded_For = "X-Forwarded-For"
+
+const X_Real_IP = "X-Real-IP"
+`,
+			expected: []string{"p", "X_Real_IP"},
+		},
+		{
+			name: "trailing comment does not apply to the next line",
+			src: `package p
+var a_b int //nolint
+var c_d int
+`,
+			expected: []string{"p", "c_d"},
+		},
+		{
+			name: "nolint linters",
+			src: `package p
+var a_b int //nolint:golint,fixname
+var c_d int //nolint:golint
+var e_f int //nolint:all
+`,
+			expected: []string{"p", "c_d"},
+		},
+		{
+			name: "categories",
+			src: `package p
+var a_b int     //fixname:ignore underscore
+var userId int  //fixname:ignore underscore
+var MAX_LEN int //fixname:ignore caps general
+`,
+			expected: []string{"p", "userId"},
+		},
+		{
+			name: "stutter",
+			src: `package user
+type UserService struct{} //fixname:ignore stutter
+type UserCache struct{}   //fixname:ignore underscore
+`,
+			expected: []string{"user", "UserCache"},
+		},
+		{
+			name: "receiver",
+			src: `package p
+type T int
+func (self T) a() {} //fixname:ignore recv
+func (my_t T) b() {} //fixname:ignore recv
+func (t T) c()    {} //fixname:ignore stutter
+`,
+			expected: []string{"p", "T", "a", "b", "c", "t"},
+		},
+		{
+			name: "package clause",
+			src: `//fixname:ignore
+package my_util
+var a_b int
+`,
+			expected: []string{"a_b"},
+		},
+		{
+			name: "file",
+			src: `//fixname:ignore-file
+
+package p
+var a_b int
+`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range testData {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "p.go", tt.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []string
+		WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+			actual = append(actual, id.Name)
+		})
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestWalkNamesErrorDirectives(t *testing.T) {
+	src := `package p
+
+import "errors"
+
+var NotFound = errors.New("not found") //fixname:ignore error
+var Closed = errors.New("closed")      //fixname:ignore caps
+var Busy = errors.New("busy")
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("p", fset, []*ast.File{f}, info); err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	WalkNames(fset, f, info, func(id *ast.Ident, thing interface{}) {
+		if _, ok := thing.(VarObj); ok {
+			actual = append(actual, id.Name)
+		}
+	})
+	expected := []string{"Closed", "Busy"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
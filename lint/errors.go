@@ -0,0 +1,162 @@
+package lint
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// checkError checks the name of the object declared by id in f against
+// golint's conventions for errors: a package-level variable of type
+// error created by errors.New or fmt.Errorf is named ErrFoo or errFoo,
+// and a package-level type implementing error FooError. It returns nil
+// for any other object.
+func checkError(f *ast.File, info *types.Info, id *ast.Ident) *Spec {
+	obj := info.Defs[id]
+	if obj == nil || obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() || id.Name == "_" {
+		return nil
+	}
+
+	var should string
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !types.Identical(obj.Type(), errorType) || !isNewError(f, info, id) {
+			return nil
+		}
+		if strings.HasPrefix(id.Name, "err") || strings.HasPrefix(id.Name, "Err") {
+			return nil
+		}
+		base := errorBase(id.Name)
+		if base == "" {
+			return nil
+		}
+		if ast.IsExported(id.Name) {
+			should = "Err" + base
+		} else {
+			should = "err" + base
+		}
+
+	case *types.TypeName:
+		if !implementsError(obj) {
+			return nil
+		}
+		if strings.HasSuffix(id.Name, "Error") || strings.HasSuffix(id.Name, "Errors") {
+			return nil
+		}
+		base := errorBase(id.Name)
+		if base == "" {
+			return nil
+		}
+		if !ast.IsExported(id.Name) {
+			r, size := utf8.DecodeRuneInString(base)
+			base = string(unicode.ToLower(r)) + base[size:]
+		}
+		should = base + "Error"
+
+	default:
+		return nil
+	}
+
+	return &Spec{
+		Id:       id,
+		To:       should,
+		Category: ErrorName,
+	}
+}
+
+// isNewError reports whether the package-level variable declared by id
+// in f has a value of its own that is a call to errors.New or
+// fmt.Errorf, rather than an error variable that may hold anything.
+func isNewError(f *ast.File, info *types.Info, id *ast.Ident) bool {
+	for _, decl := range f.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ValueSpec)
+			for i, name := range spec.Names {
+				if name != id {
+					continue
+				}
+				if len(spec.Values) != len(spec.Names) {
+					return false
+				}
+				call, ok := spec.Values[i].(*ast.CallExpr)
+				if !ok {
+					return false
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return false
+				}
+				x, ok := sel.X.(*ast.Ident)
+				if !ok {
+					return false
+				}
+				pkg, ok := info.Uses[x].(*types.PkgName)
+				if !ok {
+					return false
+				}
+				switch pkg.Imported().Path() + "." + sel.Sel.Name {
+				case "errors.New", "fmt.Errorf":
+					return true
+				}
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// implementsError reports whether the type named by obj is neither an
+// alias, a generic type nor an interface, and it or a pointer to it
+// implements error.
+func implementsError(obj *types.TypeName) bool {
+	if obj.IsAlias() {
+		return false
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+		return false
+	}
+	iface := errorType.Underlying().(*types.Interface)
+	return types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface)
+}
+
+// errorBase returns name without the Err or Error prefix and suffix
+// that name an error, fixed like Check and starting with an upper-case
+// letter, as in ErrNotFound, not_found and NotFoundError, which all give
+// NotFound. It returns "" if nothing is left.
+func errorBase(name string) string {
+	base := name
+	for _, prefix := range []string{"Error", "error", "Err", "err"} {
+		rest := strings.TrimPrefix(base, prefix)
+		if next, _ := utf8.DecodeRuneInString(rest); rest != base && (next == '_' || unicode.IsUpper(next)) {
+			base = rest
+			break
+		}
+	}
+	for _, suffix := range []string{"Error", "Err"} {
+		if rest := strings.TrimSuffix(base, suffix); rest != base && rest != "" {
+			base = rest
+			break
+		}
+	}
+	base = strings.TrimLeft(base, "_")
+	if base == "" {
+		return ""
+	}
+
+	r, size := utf8.DecodeRuneInString(base)
+	base = string(unicode.ToUpper(r)) + base[size:]
+	if spec := Check(&ast.Ident{Name: base}); spec != nil {
+		base = spec.To
+	}
+	return base
+}
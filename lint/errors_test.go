@@ -0,0 +1,131 @@
+package lint
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestErrorBase(t *testing.T) {
+	testData := []struct {
+		name     string
+		expected string
+	}{
+		{name: "NotFound", expected: "NotFound"},
+		{name: "notFound", expected: "NotFound"},
+		{name: "not_found", expected: "NotFound"},
+		{name: "ErrNotFound", expected: "NotFound"},
+		{name: "errNotFound", expected: "NotFound"},
+		{name: "NotFoundError", expected: "NotFound"},
+		{name: "NotFoundErr", expected: "NotFound"},
+		{name: "Errata", expected: "Errata"},
+		{name: "Error", expected: "Error"},
+		{name: "Http_Failure", expected: "HTTPFailure"},
+	}
+
+	for _, tt := range testData {
+		actual := errorBase(tt.name)
+		if tt.expected != actual {
+			t.Errorf("name: %s, expected: %q, got: %q", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCheckErrorNames(t *testing.T) {
+	src := `package p
+
+import (
+	"errors"
+	"fmt"
+)
+
+type stringError string
+
+func (e stringError) Error() string { return string(e) }
+
+type ErrSyntax struct{}
+
+func (e *ErrSyntax) Error() string { return "syntax" }
+
+type parse_failure struct{ error }
+
+type PathError struct{}
+
+func (e PathError) Error() string { return "path" }
+
+type Temporary interface {
+	error
+	Temporary() bool
+}
+
+type List[T any] []T
+
+func (l List[T]) Error() string { return "list" }
+
+var NotFound = errors.New("not found")
+
+var ErrClosed = errors.New("closed")
+
+var (
+	timeout       = fmt.Errorf("timeout")
+	errBusy       = errors.New("busy")
+	Default_Error error = errors.New("default")
+	_             error = PathError{}
+	config        error
+	last          error = stringError("last")
+	first, second = errors.New("first"), fmt.Errorf("second")
+)
+
+var left, right = errors.New("left"), 0
+
+var count int
+
+var perr = &ErrSyntax{}
+
+func f() {
+	var failed error
+	type local_failure struct{ error }
+	_ = local_failure{failed}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("p", fset, []*ast.File{f}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+		if spec := checkError(f, info, id); spec != nil {
+			if spec.Category != ErrorName {
+				t.Errorf("name: %s, expected category: %v, got: %v", id.Name, ErrorName, spec.Category)
+			}
+			actual = append(actual, id.Name+" -> "+spec.To)
+		}
+	})
+	expected := []string{
+		"ErrSyntax -> SyntaxError",
+		"parse_failure -> parseFailureError",
+		"NotFound -> ErrNotFound",
+		"timeout -> errTimeout",
+		"Default_Error -> ErrDefault",
+		"first -> errFirst",
+		"second -> errSecond",
+		"left -> errLeft",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,85 @@
+package lint
+
+import (
+	"go/ast"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A NameException exempts the names it matches from Check, typically
+// because they are mandated by an external protocol.
+type NameException struct {
+	// Package restricts the exception to matching package paths. It is
+	// either empty, which matches every package, a path ending in
+	// "/...", which matches that path and every path below it, or a
+	// pattern for path.Match.
+	Package string
+
+	// Name is a name or a pattern for path.Match. It is ignored if
+	// Regexp is set.
+	Name string
+
+	// Regexp matches names; it is unanchored.
+	Regexp *regexp.Regexp
+}
+
+var nameExceptions []NameException
+
+// SetNameExceptions replaces the exceptions consulted by CheckIn, in
+// addition to knownNameExceptions.
+func SetNameExceptions(excs []NameException) {
+	nameExceptions = excs
+}
+
+func (e NameException) matchPackage(pkgPath string) bool {
+	return matchPackage(e.Package, pkgPath)
+}
+
+// matchPackage reports whether pkgPath matches pattern, which is either
+// empty, a path ending in "/..." or a pattern for path.Match.
+func matchPackage(pattern, pkgPath string) bool {
+	switch {
+	case pattern == "":
+		return true
+	case strings.HasSuffix(pattern, "/..."):
+		prefix := strings.TrimSuffix(pattern, "/...")
+		return pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/")
+	default:
+		ok, _ := path.Match(pattern, pkgPath)
+		return ok
+	}
+}
+
+func (e NameException) matchName(name string) bool {
+	if e.Regexp != nil {
+		return e.Regexp.MatchString(name)
+	}
+	ok, _ := path.Match(e.Name, name)
+	return ok
+}
+
+// IsNameException reports whether name is exempted in the package with
+// the given path. Names in an external test package "p_test" are
+// exempted as in package "p".
+func IsNameException(pkgPath, name string) bool {
+	if knownNameExceptions[name] {
+		return true
+	}
+	basePath := strings.TrimSuffix(pkgPath, "_test")
+	for _, e := range nameExceptions {
+		if (e.matchPackage(pkgPath) || e.matchPackage(basePath)) && e.matchName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIn is like Check, but also honors the exceptions configured for
+// the package with the given path.
+func CheckIn(pkgPath string, id *ast.Ident) *Spec {
+	if IsNameException(pkgPath, id.Name) {
+		return nil
+	}
+	return Check(id)
+}
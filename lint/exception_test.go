@@ -0,0 +1,38 @@
+package lint
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestIsNameException(t *testing.T) {
+	defer SetNameExceptions(nil)
+	SetNameExceptions([]NameException{
+		{Name: "X_Forwarded_For"},
+		{Name: "Wire_*", Package: "example.com/proto/..."},
+		{Regexp: regexp.MustCompile(`^C_`), Package: "example.com/*/cgo"},
+	})
+
+	testData := []struct {
+		pkgPath  string
+		name     string
+		expected bool
+	}{
+		{pkgPath: "example.com/a", name: "LastInsertId", expected: true},
+		{pkgPath: "example.com/a", name: "X_Forwarded_For", expected: true},
+		{pkgPath: "example.com/a", name: "X_Forwarded_Host", expected: false},
+		{pkgPath: "example.com/proto", name: "Wire_Type", expected: true},
+		{pkgPath: "example.com/proto/v1", name: "Wire_Type", expected: true},
+		{pkgPath: "example.com/proto/v1_test", name: "Wire_Type", expected: true},
+		{pkgPath: "example.com/protocol", name: "Wire_Type", expected: false},
+		{pkgPath: "example.com/x/cgo", name: "C_int_t", expected: true},
+		{pkgPath: "example.com/x/y/cgo", name: "C_int_t", expected: false},
+	}
+
+	for _, tt := range testData {
+		actual := IsNameException(tt.pkgPath, tt.name)
+		if tt.expected != actual {
+			t.Errorf("package: %s, name: %s, expected: %v, got: %v", tt.pkgPath, tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package lint
 
 import (
+	"fmt"
 	"go/ast"
 	"regexp"
 	"strings"
@@ -48,9 +49,38 @@ var commonInitialisms = map[string]bool{
 	"XSS":   true,
 }
 
+// initialisms maps the upper-case form of each initialism recognized by
+// lintName to the form it should take in a name.
+var initialisms = DefaultInitialisms()
+
+// DefaultInitialisms returns golint's list of common initialisms.
+func DefaultInitialisms() map[string]string {
+	m := make(map[string]string, len(commonInitialisms))
+	for k := range commonInitialisms {
+		m[k] = k
+	}
+	return m
+}
+
+// SetInitialisms replaces the initialisms recognized when suggesting
+// names. Each one is given in the form it should take in a name, such
+// as "HTTP" or "OAuth".
+func SetInitialisms(list []string) {
+	m := make(map[string]string, len(list))
+	for _, s := range list {
+		m[strings.ToUpper(s)] = s
+	}
+	initialisms = m
+}
+
 var allCapsRE = regexp.MustCompile(`^[A-Z0-9_]+$`)
 
-var knownNameExceptions = map[string]bool{}
+// knownNameExceptions are names that Check never reports, wherever they
+// occur, as in golint.
+var knownNameExceptions = map[string]bool{
+	"LastInsertId": true, // must match database/sql
+	"kWh":          true,
+}
 
 func lintName(name string) (should string) {
 	// Fast path for simple cases: "_" and all lowercase.
@@ -103,13 +133,13 @@ func lintName(name string) (should string) {
 
 		// [w,i) is a word.
 		word := string(runes[w:i])
-		if u := strings.ToUpper(word); commonInitialisms[u] {
+		if u, ok := initialisms[strings.ToUpper(word)]; ok {
 			// Keep consistent case, which is lowercase only at the start.
 			if w == 0 && unicode.IsLower(runes[w]) {
 				u = strings.ToLower(u)
 			}
-			// All the common initialisms are ASCII,
-			// so we can replace the bytes exactly.
+			// Case mapping preserves the number of runes,
+			// so we can replace the runes exactly.
 			copy(runes[w:], []rune(u))
 		} else if w > 0 && strings.ToLower(word) == word {
 			// already all lowercase, and not the first word, so uppercase the first character.
@@ -144,8 +174,30 @@ const (
 	General = Category(1 + iota)
 	AllCaps
 	Underscore
+	Stutter
+	Receiver
+	ErrorName
 )
 
+func (c Category) String() string {
+	switch c {
+	case General:
+		return "general"
+	case AllCaps:
+		return "caps"
+	case Underscore:
+		return "underscore"
+	case Stutter:
+		return "stutter"
+	case Receiver:
+		return "recv"
+	case ErrorName:
+		return "error"
+	default:
+		return fmt.Sprintf("Category(%d)", c)
+	}
+}
+
 type Spec struct {
 	Id       *ast.Ident
 	To       string
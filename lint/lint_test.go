@@ -0,0 +1,77 @@
+package lint
+
+import (
+	"go/ast"
+	"testing"
+)
+
+func TestCategoryString(t *testing.T) {
+	testData := []struct {
+		category Category
+		expected string
+	}{
+		{category: General, expected: "general"},
+		{category: AllCaps, expected: "caps"},
+		{category: Underscore, expected: "underscore"},
+		{category: Stutter, expected: "stutter"},
+		{category: Receiver, expected: "recv"},
+		{category: ErrorName, expected: "error"},
+		{category: Category(0), expected: "Category(0)"},
+	}
+
+	for _, tt := range testData {
+		actual := tt.category.String()
+		if tt.expected != actual {
+			t.Errorf("category: %d, expected: %q, got: %q", tt.category, tt.expected, actual)
+		}
+	}
+}
+
+func TestLintName(t *testing.T) {
+	testData := []struct {
+		name     string
+		expected string
+	}{
+		{name: "user_id", expected: "userID"},
+		{name: "Http_Client", expected: "HTTPClient"},
+		{name: "oauth_token", expected: "oauthToken"},
+		{name: "Aws_Region", expected: "AwsRegion"},
+	}
+
+	for _, tt := range testData {
+		actual := lintName(tt.name)
+		if tt.expected != actual {
+			t.Errorf("name: %s, expected: %q, got: %q", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestSetInitialisms(t *testing.T) {
+	defer func(saved map[string]string) { initialisms = saved }(initialisms)
+	SetInitialisms([]string{"AWS", "ID", "OAuth"})
+
+	testData := []struct {
+		name     string
+		expected string
+	}{
+		{name: "user_id", expected: "userID"},
+		{name: "Http_Client", expected: "HttpClient"},
+		{name: "oauth_token", expected: "oauthToken"},
+		{name: "Oauth_Token", expected: "OAuthToken"},
+		{name: "get_oauth_token", expected: "getOAuthToken"},
+		{name: "Aws_Region", expected: "AWSRegion"},
+		{name: "AWS_REGION", expected: "AWSRegion"},
+	}
+
+	for _, tt := range testData {
+		var actual string
+		if spec := Check(&ast.Ident{Name: tt.name}); spec != nil {
+			actual = spec.To
+		} else {
+			actual = tt.name
+		}
+		if tt.expected != actual {
+			t.Errorf("name: %s, expected: %q, got: %q", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package lint
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// CheckPackageName checks a package name or an import alias, which
+// should be all lower case, without underscores or mixed caps. The name
+// of an external test package keeps its _test suffix.
+func CheckPackageName(id *ast.Ident) *Spec {
+	if id.Name == "_" || id.Name == "." {
+		return nil
+	}
+	base := strings.TrimSuffix(id.Name, "_test")
+	should := strings.ToLower(strings.Replace(base, "_", "", -1))
+	if should == base || should == "" {
+		return nil
+	}
+
+	category := General
+	if strings.Contains(base, "_") {
+		category = Underscore
+	}
+	return &Spec{
+		Id:       id,
+		To:       should + id.Name[len(base):],
+		Category: category,
+	}
+}
+
+// CheckThing is like CheckIn for a name visited by WalkNames in f, but
+// checks package names and import aliases with CheckPackageName,
+// top-level types and functions with CheckStutter first and, given
+// info, package-level variables and types for the naming of errors.
+func CheckThing(pkgPath string, f *ast.File, info *types.Info, id *ast.Ident, thing interface{}) *Spec {
+	if IsNameException(pkgPath, id.Name) {
+		return nil
+	}
+	return checkThing(f, info, id, thing)
+}
+
+func checkThing(f *ast.File, info *types.Info, id *ast.Ident, thing interface{}) *Spec {
+	if info != nil {
+		if spec := checkError(f, info, id); spec != nil {
+			return spec
+		}
+	}
+	switch thing.(type) {
+	case PackageObj, ImportAliasObj:
+		return CheckPackageName(id)
+	case TypeObj, FuncObj:
+		if isTopLevel(f, id) {
+			if spec := CheckStutter(f.Name.Name, id); spec != nil {
+				return spec
+			}
+		}
+	}
+	return Check(id)
+}
@@ -0,0 +1,46 @@
+package lint
+
+import (
+	"go/ast"
+	"testing"
+)
+
+func TestCheckPackageName(t *testing.T) {
+	testData := []struct {
+		name     string
+		expected string
+		category Category
+	}{
+		{name: "my_util", expected: "myutil", category: Underscore},
+		{name: "myUtil", expected: "myutil", category: General},
+		{name: "my_util_test", expected: "myutil_test", category: Underscore},
+		{name: "myutil_test", expected: ""},
+		{name: "strconv", expected: ""},
+		{name: "_", expected: ""},
+	}
+
+	for _, tt := range testData {
+		spec := CheckPackageName(&ast.Ident{Name: tt.name})
+		var actual string
+		if spec != nil {
+			actual = spec.To
+			if spec.Category != tt.category {
+				t.Errorf("name: %s, expected category: %v, got: %v", tt.name, tt.category, spec.Category)
+			}
+		}
+		if tt.expected != actual {
+			t.Errorf("name: %s, expected: %q, got: %q", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCheckThing(t *testing.T) {
+	file := &ast.File{Name: ast.NewIdent("p")}
+	id := &ast.Ident{Name: "str_conv"}
+	if spec := CheckThing("p", file, nil, id, ImportAliasObj{}); spec == nil || spec.To != "strconv" {
+		t.Errorf("import alias: expected strconv, got: %v", spec)
+	}
+	if spec := CheckThing("p", file, nil, id, VarObj{}); spec == nil || spec.To != "strConv" {
+		t.Errorf("var: expected strConv, got: %v", spec)
+	}
+}
@@ -0,0 +1,193 @@
+package lint
+
+import (
+	"go/ast"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// genericReceiverNames are receiver names that say nothing about the
+// receiver, which golint warns about.
+var genericReceiverNames = map[string]bool{
+	"this": true,
+	"self": true,
+}
+
+// A ReceiverName configures the receiver name of the methods of the
+// types it matches.
+type ReceiverName struct {
+	// Package restricts the configuration to matching package paths,
+	// like NameException.Package.
+	Package string
+
+	// Type is the name of the receiver base type or a pattern for
+	// path.Match.
+	Type string
+
+	// Name is the receiver name.
+	Name string
+}
+
+var receiverNames []ReceiverName
+
+// SetReceiverNames replaces the receiver names consulted by
+// Receivers.Check.
+func SetReceiverNames(names []ReceiverName) {
+	receiverNames = names
+}
+
+// configuredReceiverName returns the receiver name configured for the
+// type typeName in the package with the given path, or "" if there is
+// none. The first matching configuration wins.
+func configuredReceiverName(pkgPath, typeName string) string {
+	basePath := strings.TrimSuffix(pkgPath, "_test")
+	for _, n := range receiverNames {
+		if !matchPackage(n.Package, pkgPath) && !matchPackage(n.Package, basePath) {
+			continue
+		}
+		if ok, _ := path.Match(n.Type, typeName); ok {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+// Receivers collects the receivers of the methods of a package, as
+// visited by WalkNames, to check that those of the methods of the same
+// type are named alike.
+type Receivers struct {
+	pkgPath string
+	types   []string
+	ids     map[string][]*ast.Ident
+	methods map[*ast.Ident]*ast.FuncDecl
+}
+
+// NewReceivers returns an empty collection of the receivers of the
+// package with the given path.
+func NewReceivers(pkgPath string) *Receivers {
+	return &Receivers{
+		pkgPath: pkgPath,
+		ids:     make(map[string][]*ast.Ident),
+		methods: make(map[*ast.Ident]*ast.FuncDecl),
+	}
+}
+
+// Add adds the receiver id visited by WalkNames with thing.
+func (r *Receivers) Add(id *ast.Ident, thing ReceiverObj) {
+	if id.Name == "_" || thing.Type == "" {
+		return
+	}
+	if _, ok := r.ids[thing.Type]; !ok {
+		r.types = append(r.types, thing.Type)
+	}
+	r.ids[thing.Type] = append(r.ids[thing.Type], id)
+	r.methods[id] = thing.Method
+}
+
+// Check returns the renames that give the receivers of the methods of
+// each type the same name, in the order the receivers were added. The
+// name is the one configured for the type if any, or else the most
+// common one among the receivers as Check would fix them, leaving out
+// this and self, or else the first letter of the type name. Receivers
+// exempted by a NameException are left as they are, and so are those of
+// methods that already use the name, which they would conflict with.
+func (r *Receivers) Check() []*Spec {
+	var specs []*Spec
+	for _, typeName := range r.types {
+		ids := r.ids[typeName]
+		name := configuredReceiverName(r.pkgPath, typeName)
+		if name == "" {
+			name = dominantReceiverName(ids)
+		}
+		if name == "" {
+			name = defaultReceiverName(typeName)
+		}
+		if name == "" {
+			continue
+		}
+
+		for _, id := range ids {
+			if id.Name == name || IsNameException(r.pkgPath, id.Name) || usesName(r.methods[id], name) {
+				continue
+			}
+			if spec := Check(id); spec != nil && spec.To == name {
+				specs = append(specs, spec)
+				continue
+			}
+			specs = append(specs, &Spec{
+				Id:       id,
+				To:       name,
+				Category: Receiver,
+			})
+		}
+	}
+	return specs
+}
+
+// usesName reports whether an identifier other than a selected field
+// or method is named name in the signature or body of method, as a
+// parameter, a local or a reference to something declared outside it.
+func usesName(method *ast.FuncDecl, name string) bool {
+	if method == nil {
+		return false
+	}
+	var found bool
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// Sel names a field or method.
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.Ident:
+			found = found || n.Name == name
+		}
+		return !found
+	}
+	ast.Inspect(method.Type, visit)
+	if method.Body != nil {
+		ast.Inspect(method.Body, visit)
+	}
+	return found
+}
+
+// dominantReceiverName returns the most common name among ids as Check
+// would fix them, other than a generic one, preferring the first in
+// case of a tie, or "" if there is none.
+func dominantReceiverName(ids []*ast.Ident) string {
+	counts := make(map[string]int)
+	var names []string
+	for _, id := range ids {
+		name := id.Name
+		if spec := Check(id); spec != nil {
+			name = spec.To
+		}
+		if genericReceiverNames[strings.ToLower(name)] {
+			continue
+		}
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+		counts[name]++
+	}
+
+	var dominant string
+	for _, name := range names {
+		if counts[name] > counts[dominant] {
+			dominant = name
+		}
+	}
+	return dominant
+}
+
+// defaultReceiverName returns the first letter of typeName in lower
+// case, or "" if it does not start with a letter.
+func defaultReceiverName(typeName string) string {
+	r, _ := utf8.DecodeRuneInString(typeName)
+	if !unicode.IsLetter(r) {
+		return ""
+	}
+	return string(unicode.ToLower(r))
+}
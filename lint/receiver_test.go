@@ -0,0 +1,132 @@
+package lint
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestReceivers(t *testing.T) {
+	testData := []struct {
+		name     string
+		src      string
+		config   []ReceiverName
+		expected []string
+	}{
+		{
+			name: "dominant",
+			src: `package p
+type Server struct{}
+func (s *Server) a()   {}
+func (srv *Server) b() {}
+func (s *Server) c()   {}
+`,
+			expected: []string{"srv -> s"},
+		},
+		{
+			name: "tie",
+			src: `package p
+type Server struct{}
+func (srv *Server) a() {}
+func (s *Server) b()   {}
+`,
+			expected: []string{"s -> srv"},
+		},
+		{
+			name: "generic names",
+			src: `package p
+type Server struct{}
+func (this *Server) a() {}
+func (self *Server) b() {}
+func (srv *Server) c()  {}
+`,
+			expected: []string{"this -> srv", "self -> srv"},
+		},
+		{
+			name: "only generic names",
+			src: `package p
+type Server struct{}
+func (this *Server) a() {}
+`,
+			expected: []string{"this -> s"},
+		},
+		{
+			name: "fixed by Check",
+			src: `package p
+type Server struct{}
+func (my_srv *Server) a() {}
+func (mySrv *Server) b()  {}
+`,
+			expected: []string{"my_srv -> mySrv (underscore)"},
+		},
+		{
+			name: "per type",
+			src: `package p
+type A struct{}
+type B struct{}
+func (x A) a() {}
+func (y B) b() {}
+func (_ B) c() {}
+`,
+			expected: nil,
+		},
+		{
+			name: "configured",
+			src: `package p
+type Server struct{}
+func (s *Server) a() {}
+func (s *Server) b() {}
+`,
+			config:   []ReceiverName{{Type: "Serv*", Name: "srv"}},
+			expected: []string{"s -> srv", "s -> srv"},
+		},
+		{
+			name: "name in use",
+			src: `package p
+type Client struct{ c string }
+func (c *Client) a() {}
+func (client *Client) b(c string) bool { return client.c == c }
+func (this *Client) d() { _ = this.c }
+`,
+			expected: []string{"this -> c"},
+		},
+		{
+			name: "configured for another package",
+			src: `package p
+type Server struct{}
+func (s *Server) a() {}
+`,
+			config:   []ReceiverName{{Package: "example.com/q/...", Type: "*", Name: "x"}},
+			expected: nil,
+		},
+	}
+
+	defer SetReceiverNames(nil)
+	for _, tt := range testData {
+		SetReceiverNames(tt.config)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "p.go", tt.src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewReceivers("example.com/p")
+		WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+			if recv, ok := thing.(ReceiverObj); ok {
+				r.Add(id, recv)
+			}
+		})
+		var actual []string
+		for _, spec := range r.Check() {
+			s := spec.Id.Name + " -> " + spec.To
+			if spec.Category != Receiver {
+				s += " (" + spec.Category.String() + ")"
+			}
+			actual = append(actual, s)
+		}
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package lint
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CheckStutter checks the name of a type or function declared at the
+// top level of a package named pkgName, which other packages refer to
+// as pkgName.Name. As in golint, the name stutters if the package name
+// is a strict prefix of it, regardless of case, followed by the start
+// of a new word, as in http.HTTPServer or user.UserService. The
+// suggestion strips the prefix and fixes the rest of the name like
+// Check.
+func CheckStutter(pkgName string, id *ast.Ident) *Spec {
+	name := id.Name
+	if pkgName == "main" || !ast.IsExported(name) || len(name) <= len(pkgName) {
+		return nil
+	}
+	if !strings.EqualFold(pkgName, name[:len(pkgName)]) {
+		return nil
+	}
+	rem := name[len(pkgName):]
+	if next, _ := utf8.DecodeRuneInString(rem); next != '_' && !unicode.IsUpper(next) {
+		return nil
+	}
+
+	rem = strings.TrimLeft(rem, "_")
+	if !ast.IsExported(rem) {
+		// e.g. HTTP_2
+		return nil
+	}
+	if spec := Check(&ast.Ident{Name: rem}); spec != nil {
+		rem = spec.To
+	}
+	return &Spec{
+		Id:       id,
+		To:       rem,
+		Category: Stutter,
+	}
+}
+
+// isTopLevel reports whether id names a function or type declared at
+// the top level of f.
+func isTopLevel(f *ast.File, id *ast.Ident) bool {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name == id {
+				return d.Recv == nil
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if spec.(*ast.TypeSpec).Name == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
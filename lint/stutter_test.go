@@ -0,0 +1,73 @@
+package lint
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestCheckStutter(t *testing.T) {
+	testData := []struct {
+		pkg      string
+		name     string
+		expected string
+	}{
+		{pkg: "user", name: "UserService", expected: "Service"},
+		{pkg: "http", name: "HTTPServer", expected: "Server"},
+		{pkg: "http", name: "HttpServer", expected: "Server"},
+		{pkg: "user", name: "User_Service_Id", expected: "ServiceID"},
+		{pkg: "user", name: "User", expected: ""},
+		{pkg: "user", name: "Users", expected: ""},
+		{pkg: "user", name: "userService", expected: ""},
+		{pkg: "http", name: "HTTP_2", expected: ""},
+		{pkg: "main", name: "MainLoop", expected: ""},
+	}
+
+	for _, tt := range testData {
+		spec := CheckStutter(tt.pkg, &ast.Ident{Name: tt.name})
+		var actual string
+		if spec != nil {
+			actual = spec.To
+			if spec.Category != Stutter {
+				t.Errorf("name: %s.%s, expected category: %v, got: %v", tt.pkg, tt.name, Stutter, spec.Category)
+			}
+		}
+		if tt.expected != actual {
+			t.Errorf("name: %s.%s, expected: %q, got: %q", tt.pkg, tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCheckThingStutter(t *testing.T) {
+	src := `package user
+
+type UserService struct{}
+
+func UserName() string {
+	type UserID int
+	return ""
+}
+
+func (s UserService) UserCount() int { return 0 }
+
+var UserCache int
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "user.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+		if spec := CheckThing("example.com/user", f, nil, id, thing); spec != nil && spec.Category == Stutter {
+			actual = append(actual, id.Name+" -> "+spec.To)
+		}
+	})
+	expected := []string{"UserService -> Service", "UserName -> Name"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
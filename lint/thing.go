@@ -2,6 +2,7 @@ package lint
 
 import (
 	"fmt"
+	"go/ast"
 )
 
 type Var interface {
@@ -20,6 +21,40 @@ type RangeVarObj struct {
 
 func (obj RangeVarObj) String() string { return "range var" }
 
+// A ReceiverObj is the receiver of the method Method whose receiver
+// base type is named Type.
+type ReceiverObj struct {
+	VarObj
+	Type   string
+	Method *ast.FuncDecl
+}
+
+func (obj ReceiverObj) String() string { return "receiver" }
+
+type TypeSwitchVarObj struct {
+	VarObj
+}
+
+func (obj TypeSwitchVarObj) String() string { return "type switch var" }
+
+type SelectVarObj struct {
+	VarObj
+}
+
+func (obj SelectVarObj) String() string { return "select var" }
+
+type LabelObj struct{}
+
+func (obj LabelObj) String() string { return "label" }
+
+type PackageObj struct{}
+
+func (obj PackageObj) String() string { return "package" }
+
+type ImportAliasObj struct{}
+
+func (obj ImportAliasObj) String() string { return "import alias" }
+
 type ConstObj struct{}
 
 func (obj ConstObj) String() string { return "const" }
@@ -32,12 +67,17 @@ type StructFieldObj struct{}
 
 func (obj StructFieldObj) String() string { return "struct field" }
 
+type TypeParamObj struct{}
+
+func (obj TypeParamObj) String() string { return "type parameter" }
+
 type ObjKind int
 
 const (
 	isFunc = ObjKind(iota + 1)
 	isMethod
 	isInterfaceMethod
+	isFuncLit
 )
 
 func (k ObjKind) String() string {
@@ -48,6 +88,8 @@ func (k ObjKind) String() string {
 		return "method"
 	case isInterfaceMethod:
 		return "interface method"
+	case isFuncLit:
+		return "func literal"
 	default:
 		return fmt.Sprintf("ObjKind(%d)", k)
 	}
@@ -56,6 +98,7 @@ func (k ObjKind) String() string {
 func (k ObjKind) OfFunc() bool            { return k == isFunc }
 func (k ObjKind) OfMethod() bool          { return k == isMethod }
 func (k ObjKind) OfInterfaceMethod() bool { return k == isInterfaceMethod }
+func (k ObjKind) OfFuncLit() bool         { return k == isFuncLit }
 
 type FuncObj struct {
 	ObjKind
@@ -119,6 +162,12 @@ func NewInterfaceMethodParameterVarObj() ParameterVarObj {
 	}
 }
 
+func NewFuncLitParameterVarObj() ParameterVarObj {
+	return ParameterVarObj{
+		ObjKind: isFuncLit,
+	}
+}
+
 func NewFunctionResultVarObj() ResultVarObj {
 	return ResultVarObj{
 		ObjKind: isFunc,
@@ -136,3 +185,9 @@ func NewInterfaceMethodResultVarObj() ResultVarObj {
 		ObjKind: isInterfaceMethod,
 	}
 }
+
+func NewFuncLitResultVarObj() ResultVarObj {
+	return ResultVarObj{
+		ObjKind: isFuncLit,
+	}
+}
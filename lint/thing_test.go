@@ -30,6 +30,26 @@ func TestObjString(t *testing.T) {
 			obj:      StructFieldObj{},
 			expected: "struct field",
 		},
+		{
+			obj:      TypeParamObj{},
+			expected: "type parameter",
+		},
+		{
+			obj:      ReceiverObj{},
+			expected: "receiver",
+		},
+		{
+			obj:      TypeSwitchVarObj{},
+			expected: "type switch var",
+		},
+		{
+			obj:      SelectVarObj{},
+			expected: "select var",
+		},
+		{
+			obj:      LabelObj{},
+			expected: "label",
+		},
 		{
 			obj:      FuncObj{ObjKind: isFunc},
 			expected: "func",
@@ -62,6 +82,10 @@ func TestObjString(t *testing.T) {
 			obj:      ResultVarObj{ObjKind: isInterfaceMethod},
 			expected: "interface method result",
 		},
+		{
+			obj:      ParameterVarObj{ObjKind: isFuncLit},
+			expected: "func literal parameter",
+		},
 	}
 
 	for _, tt := range testData {
@@ -100,34 +124,61 @@ func TestResultVarObjIsVar(t *testing.T) {
 	}
 }
 
+func TestReceiverObjIsVar(t *testing.T) {
+	var obj interface{} = ReceiverObj{}
+	if _, ok := obj.(Var); !ok {
+		t.Errorf("%v is not a Var", obj)
+	}
+}
+
+func TestTypeSwitchVarObjIsVar(t *testing.T) {
+	var obj interface{} = TypeSwitchVarObj{}
+	if _, ok := obj.(Var); !ok {
+		t.Errorf("%v is not a Var", obj)
+	}
+}
+
+func TestSelectVarObjIsVar(t *testing.T) {
+	var obj interface{} = SelectVarObj{}
+	if _, ok := obj.(Var); !ok {
+		t.Errorf("%v is not a Var", obj)
+	}
+}
+
 func TestParameterVarObjKind(t *testing.T) {
 	tableData := []struct {
 		name     string
 		objKind  ObjKind
-		expected [3]bool
+		expected [4]bool
 	}{
 		{
 			name:     "isFunc",
 			objKind:  isFunc,
-			expected: [3]bool{true, false, false},
+			expected: [4]bool{true, false, false, false},
 		},
 		{
 			name:     "isMethod",
 			objKind:  isMethod,
-			expected: [3]bool{false, true, false},
+			expected: [4]bool{false, true, false, false},
 		},
 		{
 			name:     "isInterfaceMethod",
 			objKind:  isInterfaceMethod,
-			expected: [3]bool{false, false, true},
+			expected: [4]bool{false, false, true, false},
+		},
+		{
+			name:     "isFuncLit",
+			objKind:  isFuncLit,
+			expected: [4]bool{false, false, false, true},
 		},
 	}
 	for _, tt := range tableData {
 		obj := ParameterVarObj{ObjKind: tt.objKind}
-		result := [3]bool{
+		result := [4]bool{
 			obj.OfFunc(),
 			obj.OfMethod(),
 			obj.OfInterfaceMethod(),
+			obj.OfFuncLit(),
 		}
 		if result != tt.expected {
 			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, result)
@@ -139,30 +190,36 @@ func TestResultVarObjKind(t *testing.T) {
 	tableData := []struct {
 		name     string
 		objKind  ObjKind
-		expected [3]bool
+		expected [4]bool
 	}{
 		{
 			name:     "isFunc",
 			objKind:  isFunc,
-			expected: [3]bool{true, false, false},
+			expected: [4]bool{true, false, false, false},
 		},
 		{
 			name:     "isMethod",
 			objKind:  isMethod,
-			expected: [3]bool{false, true, false},
+			expected: [4]bool{false, true, false, false},
 		},
 		{
 			name:     "isInterfaceMethod",
 			objKind:  isInterfaceMethod,
-			expected: [3]bool{false, false, true},
+			expected: [4]bool{false, false, true, false},
+		},
+		{
+			name:     "isFuncLit",
+			objKind:  isFuncLit,
+			expected: [4]bool{false, false, false, true},
 		},
 	}
 	for _, tt := range tableData {
 		obj := ResultVarObj{ObjKind: tt.objKind}
-		result := [3]bool{
+		result := [4]bool{
 			obj.OfFunc(),
 			obj.OfMethod(),
 			obj.OfInterfaceMethod(),
+			obj.OfFuncLit(),
 		}
 		if result != tt.expected {
 			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, result)
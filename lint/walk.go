@@ -3,6 +3,7 @@ package lint
 import (
 	"go/ast"
 	"go/token"
+	"go/types"
 	"strings"
 )
 
@@ -23,7 +24,27 @@ func isTest(filename string) bool {
 	return strings.HasSuffix(filename, "_test.go")
 }
 
-func WalkNames(fset *token.FileSet, astfile *ast.File, visit func(id *ast.Ident, thing interface{})) {
+// WalkNames calls visit for each name declared in astfile, including
+// its package name and import aliases, except for those suppressed by a
+// //fixname:ignore or //nolint directive on their line or the line
+// above, and for all of them if the file contains a
+// //fixname:ignore-file directive. The categories of a directive are
+// matched against CheckThing given info, which may be nil.
+func WalkNames(fset *token.FileSet, astfile *ast.File, info *types.Info, visit func(id *ast.Ident, thing interface{})) {
+	lines, skip := directives(fset, astfile)
+	if skip {
+		return
+	}
+	if lines != nil {
+		next := visit
+		visit = func(id *ast.Ident, thing interface{}) {
+			if d := lines[fset.Position(id.Pos()).Line]; d != nil && d.suppresses(astfile, info, id, thing) {
+				return
+			}
+			next(id, thing)
+		}
+	}
+
 	visitList := func(fl *ast.FieldList, thing interface{}) {
 		if fl == nil {
 			return
@@ -35,11 +56,24 @@ func WalkNames(fset *token.FileSet, astfile *ast.File, visit func(id *ast.Ident,
 		}
 	}
 
+	// short variable declarations visited with a more specific thing
+	bindings := make(map[*ast.AssignStmt]bool)
+	visitBinding := func(stmt ast.Stmt, thing interface{}) {
+		if a, ok := stmt.(*ast.AssignStmt); ok && a.Tok == token.DEFINE {
+			bindings[a] = true
+			for _, exp := range a.Lhs {
+				if id, ok := exp.(*ast.Ident); ok {
+					visit(id, thing)
+				}
+			}
+		}
+	}
+
 	fn := func(node ast.Node) bool {
 		switch v := node.(type) {
 		case *ast.AssignStmt:
 			// local variable assignment
-			if v.Tok == token.ASSIGN {
+			if v.Tok != token.DEFINE || bindings[v] {
 				return true
 			}
 			for _, exp := range v.Lhs {
@@ -48,6 +82,17 @@ func WalkNames(fset *token.FileSet, astfile *ast.File, visit func(id *ast.Ident,
 				}
 			}
 
+		case *ast.TypeSwitchStmt:
+			// switch x := y.(type)
+			visitBinding(v.Assign, TypeSwitchVarObj{})
+
+		case *ast.CommClause:
+			// case x := <-ch
+			visitBinding(v.Comm, SelectVarObj{})
+
+		case *ast.LabeledStmt:
+			visit(v.Label, LabelObj{})
+
 		case *ast.RangeStmt:
 			// local variable assignment in range statement
 			if v.Tok == token.ASSIGN {
@@ -81,13 +126,37 @@ func WalkNames(fset *token.FileSet, astfile *ast.File, visit func(id *ast.Ident,
 			// global
 			visit(v.Name, FuncObj{ObjKind: kind})
 
+			if v.Recv != nil && len(v.Recv.List) > 0 {
+				visitList(v.Recv, ReceiverObj{Type: recvTypeName(v.Recv.List[0].Type), Method: v})
+			}
+
+			// type parameters, including those a method declares for
+			// its receiver
+			if v.Recv != nil && len(v.Recv.List) > 0 {
+				for _, id := range recvTypeParams(v.Recv.List[0].Type) {
+					visit(id, TypeParamObj{})
+				}
+			}
+			visitList(v.Type.TypeParams, TypeParamObj{})
+
 			// local
 			visitList(v.Type.Params, ParameterVarObj{ObjKind: kind})
 			visitList(v.Type.Results, ResultVarObj{ObjKind: kind})
 
+		case *ast.FuncLit:
+			// function literal
+			visitList(v.Type.Params, ParameterVarObj{ObjKind: isFuncLit})
+			visitList(v.Type.Results, ResultVarObj{ObjKind: isFuncLit})
+
 		case *ast.GenDecl:
 			// general declaration (global/local)
 			if v.Tok == token.IMPORT {
+				for _, spec := range v.Specs {
+					s := spec.(*ast.ImportSpec)
+					if s.Name != nil && s.Name.Name != "_" && s.Name.Name != "." {
+						visit(s.Name, ImportAliasObj{})
+					}
+				}
 				return true
 			}
 			var thing interface{}
@@ -103,6 +172,7 @@ func WalkNames(fset *token.FileSet, astfile *ast.File, visit func(id *ast.Ident,
 				switch s := spec.(type) {
 				case *ast.TypeSpec:
 					visit(s.Name, thing)
+					visitList(s.TypeParams, TypeParamObj{})
 				case *ast.ValueSpec:
 					for _, id := range s.Names {
 						visit(id, thing)
@@ -136,5 +206,47 @@ func WalkNames(fset *token.FileSet, astfile *ast.File, visit func(id *ast.Ident,
 		return true
 	}
 
+	visit(astfile.Name, PackageObj{})
 	nodeWalker(fn).Walk(astfile)
 }
+
+// recvTypeName returns the name of the base type of a receiver type
+// such as *List[T].
+func recvTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return recvTypeName(e.X)
+	case *ast.ParenExpr:
+		return recvTypeName(e.X)
+	case *ast.IndexExpr:
+		return recvTypeName(e.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(e.X)
+	}
+	return ""
+}
+
+// recvTypeParams returns the type parameters declared by a receiver
+// type such as *List[T].
+func recvTypeParams(expr ast.Expr) []*ast.Ident {
+	var indices []ast.Expr
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return recvTypeParams(e.X)
+	case *ast.ParenExpr:
+		return recvTypeParams(e.X)
+	case *ast.IndexExpr:
+		indices = []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		indices = e.Indices
+	}
+	var ids []*ast.Ident
+	for _, index := range indices {
+		if id, ok := index.(*ast.Ident); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,138 @@
+package lint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestWalkNamesTypeParams(t *testing.T) {
+	src := `package p
+
+type Pair[Key_Type comparable, Val_Type any] struct {
+	First_Key Key_Type
+}
+
+func (p *Pair[K_T, V]) Get_Key() K_T { return p.First_Key }
+
+func Map_Keys[Key_Type comparable, V any](m map[Key_Type]V) []Key_Type { return nil }
+`
+	expected := []string{
+		"package p",
+		"type Pair",
+		"type parameter Key_Type",
+		"type parameter Val_Type",
+		"struct field First_Key",
+		"method Get_Key",
+		"receiver p",
+		"type parameter K_T",
+		"type parameter V",
+		"func Map_Keys",
+		"type parameter Key_Type",
+		"type parameter V",
+		"func parameter m",
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+		actual = append(actual, fmt.Sprintf("%s %s", thing, id.Name))
+	})
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestWalkNamesStatements(t *testing.T) {
+	src := `package p
+
+import (
+	_ "embed"
+	str_conv "strconv"
+)
+
+func (my_srv *Server) Run(in chan int, x interface{}) {
+	count := 0
+	count += 1
+outer_loop:
+	for {
+		select {
+		case in_val := <-in:
+			_ = in_val
+			break outer_loop
+		}
+	}
+	switch x_val := x.(type) {
+	case int:
+		_ = x_val
+	}
+	f := func(user_id int) (err_val error) { return nil }
+	_ = f
+}
+`
+	expected := []string{
+		"package p",
+		"import alias str_conv",
+		"method Run",
+		"receiver my_srv",
+		"method parameter in",
+		"method parameter x",
+		"var count",
+		"label outer_loop",
+		"select var in_val",
+		"type switch var x_val",
+		"var f",
+		"func literal parameter user_id",
+		"func literal result err_val",
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+		actual = append(actual, fmt.Sprintf("%s %s", thing, id.Name))
+	})
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestWalkNamesReceiverTypes(t *testing.T) {
+	src := `package p
+
+type Server struct{}
+type List[T any] []T
+type Pair[K, V any] struct{}
+
+func (s Server) a()       {}
+func (l *List[T]) b()     {}
+func (p *Pair[K, V]) c()  {}
+func (s *(Server)) d()    {}
+`
+	expected := []string{"Server", "List", "Pair", "Server"}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+		if recv, ok := thing.(ReceiverObj); ok {
+			actual = append(actual, recv.Type)
+		}
+	})
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
@@ -1,110 +1,219 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"go/ast"
-	"go/build"
-	"go/parser"
+	"go/token"
 	"go/types"
+	"io/ioutil"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
-	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
 
+	"github.com/knzm/go-fixname/config"
 	"github.com/knzm/go-fixname/lint"
 	"github.com/knzm/go-fixname/rename"
 )
 
-func containsHardErrors(errors []error) bool {
-	for _, err := range errors {
-		if err, ok := err.(types.Error); ok && err.Soft {
+// hardErrors returns the errors of pkg, except for the "soft" type
+// errors that go/types reports but gc does not (Go issue 14596).
+func hardErrors(pkg *packages.Package) []packages.Error {
+	soft := make(map[string]bool)
+	for _, err := range pkg.TypeErrors {
+		if err.Soft {
+			soft[err.Fset.Position(err.Pos).String()+": "+err.Msg] = true
+		}
+	}
+
+	var errs []packages.Error
+	for _, err := range pkg.Errors {
+		if err.Kind == packages.TypeError && soft[err.Pos+": "+err.Msg] {
 			continue
 		}
-		return true
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// A loadError reports the packages that failed to type-check.
+type loadError struct {
+	pkgs []string
+	errs map[string][]packages.Error
+}
+
+func (e *loadError) Error() string {
+	errpkgs := e.pkgs
+	var more string
+	if len(errpkgs) > 3 {
+		more = fmt.Sprintf(" and %d more", len(errpkgs)-3)
+		errpkgs = errpkgs[:3]
 	}
-	return false
+	return fmt.Sprintf("couldn't load packages due to errors: %s%s",
+		strings.Join(errpkgs, ", "), more)
 }
 
-func loadProgram(pkgs map[string]bool, verbose bool) (*loader.Program, error) {
-	conf := loader.Config{
-		Build:      &build.Default,
-		ParserMode: parser.ParseComments,
+const loadMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedCompiledGoFiles |
+	packages.NeedImports |
+	packages.NeedTypes |
+	packages.NeedTypesSizes |
+	packages.NeedSyntax |
+	packages.NeedTypesInfo |
+	packages.NeedModule | packages.NeedEmbedFiles
+
+// loadPackages loads the packages matching patterns, together with
+// their test variants, from source. Patterns are resolved by the go
+// command, so modules, replace directives and go.work are honored.
+// Files in overlay are read from memory instead of from disk.
+func loadPackages(patterns []string, overlay map[string][]byte, verbose bool) (*token.FileSet, []*packages.Package, error) {
+	fset := token.NewFileSet()
+	conf := &packages.Config{
+		Mode:    loadMode,
+		Fset:    fset,
+		Tests:   true,
+		Overlay: overlay,
+	}
+	initial, err := packages.Load(conf, patterns...)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		// TODO(adonovan): enable this.  Requires making a lot of code more robust!
-		AllowErrors: false,
+	var pkgs []*packages.Package
+	for _, pkg := range initial {
+		// Skip the synthesized main packages of test binaries.
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].ID < pkgs[j].ID
+	})
+	if len(pkgs) == 0 {
+		return nil, nil, fmt.Errorf("no packages matching %s", strings.Join(patterns, " "))
 	}
-	// Optimization: don't type-check the bodies of functions in our
-	// dependencies, since we only need exported package members.
-	conf.TypeCheckFuncBodies = func(p string) bool {
-		return pkgs[p] || pkgs[strings.TrimSuffix(p, "_test")]
+
+	lerr := &loadError{errs: make(map[string][]packages.Error)}
+	// Report hard errors in indirectly imported packages.
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if errs := hardErrors(pkg); len(errs) > 0 {
+			lerr.pkgs = append(lerr.pkgs, pkg.ID)
+			lerr.errs[pkg.ID] = errs
+		}
+	})
+	if lerr.pkgs != nil {
+		sort.Strings(lerr.pkgs)
+		return nil, nil, lerr
 	}
 
 	if verbose {
-		conf.AfterTypeCheck = func(info *loader.PackageInfo, files []*ast.File) {
-			log.Println("Checked:", info)
+		for _, pkg := range pkgs {
+			log.Println("Checked:", pkg.ID)
 		}
 	}
+	return fset, pkgs, nil
+}
 
-	for pkg := range pkgs {
-		conf.ImportWithTests(pkg)
+// verifyPackages reloads the packages with the renamed files in place
+// of the originals and reports every hard error, package by package.
+func verifyPackages(patterns []string, contents map[string][]byte, verbose bool) error {
+	_, _, err := loadPackages(patterns, contents, verbose)
+	lerr, ok := err.(*loadError)
+	if !ok {
+		return err
 	}
 
-	// Ideally we would just return conf.Load() here, but go/types
-	// reports certain "soft" errors that gc does not (Go issue 14596).
-	// As a workaround, we set AllowErrors=true and then duplicate
-	// the loader's error checking but allow soft errors.
-	// It would be nice if the loader API permitted "AllowErrors: soft".
-	conf.AllowErrors = true
-	prog, err := conf.Load()
-	if err != nil {
-		return nil, err
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "renamed program fails to type-check; no files were changed")
+	for _, id := range lerr.pkgs {
+		fmt.Fprintf(&buf, "\n%s:", id)
+		for _, err := range lerr.errs[id] {
+			fmt.Fprintf(&buf, "\n\t%s", err)
+		}
 	}
+	return errors.New(buf.String())
+}
 
-	var errpkgs []string
-	// Report hard errors in indirectly imported packages.
-	for _, info := range prog.AllPackages {
-		if containsHardErrors(info.Errors) {
-			errpkgs = append(errpkgs, info.Pkg.Path())
+// rootDir returns the directory that paths in patches and reports are
+// relative to: the root of the enclosing git repository if there is
+// one, and the main module directory or the current directory otherwise.
+func rootDir(pkgs []*packages.Package) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := wd; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
 		}
+		dir = parent
 	}
-	if errpkgs != nil {
-		var more string
-		if len(errpkgs) > 3 {
-			more = fmt.Sprintf(" and %d more", len(errpkgs)-3)
-			errpkgs = errpkgs[:3]
+	for _, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Main && pkg.Module.Dir != "" {
+			return pkg.Module.Dir, nil
 		}
-		return nil, fmt.Errorf("couldn't load packages due to errors: %s%s",
-			strings.Join(errpkgs, ", "), more)
 	}
-	return prog, nil
+	return wd, nil
 }
 
 type Option struct {
-	inplace bool
-	check   bool
-	verbose bool
-	filter  Filter
-	args    []string
+	inplace  bool
+	check    bool
+	exported bool
+	aliases  bool
+	manifest string
+	apply    string
+	tags     []string
+	strings  bool
+	comments bool
+	patch    string
+	format   string
+	config   string
+	verify   bool
+	verbose  bool
+	filter   Filter
+	args     []string
+}
+
+// loadConfig applies the configuration file given by -config, or else
+// the one found from the current directory upward, if any.
+func loadConfig(filename string, verbose bool) error {
+	c, filename, err := config.LoadDefault(filename)
+	if err != nil || c == nil {
+		return err
+	}
+	if verbose {
+		log.Println("Using config:", filename)
+	}
+	return c.Apply()
 }
 
 func Main(option *Option) error {
-	pkgs := make(map[string]bool)
-	for _, arg := range option.args {
-		pkgs[arg] = true
+	if err := loadConfig(option.config, option.verbose); err != nil {
+		return err
 	}
 
-	iprog, err := loadProgram(pkgs, option.verbose)
+	fset, pkgs, err := loadPackages(option.args, nil, option.verbose)
 	if err != nil {
 		return err
 	}
 
-	renamer := rename.New(iprog)
+	renamer := rename.New(fset, pkgs)
 
+	var patch bytes.Buffer
 	writeFunc := rename.Diff
 	verbose := option.verbose
 	quiet := true
@@ -116,54 +225,263 @@ func Main(option *Option) error {
 	} else if option.inplace {
 		writeFunc = rename.WriteFile
 		quiet = false
+	} else if option.patch != "" {
+		root, err := rootDir(pkgs)
+		if err != nil {
+			return err
+		}
+		writeFunc = rename.PatchTo(&patch, root, rename.DiffContext)
+		quiet = false
+	}
+	var verifyFailed bool
+	if option.verify && !option.check {
+		renamer.SetVerifyFunc(func(contents map[string][]byte) error {
+			err := verifyPackages(option.args, contents, verbose)
+			verifyFailed = err != nil
+			return err
+		})
+	}
+	if !option.check && !option.exported {
+		renamer.SetProtected(renamer.IsAPI)
 	}
+	renamer.SetAliases(option.aliases)
+	renamer.SetTags(option.tags)
+	renamer.SetRewriteStrings(option.strings)
+	renamer.SetRewriteComments(option.comments)
 	renamer.SetWriteFunc(writeFunc)
 	renamer.SetVerbose(verbose)
 	renamer.SetQuiet(quiet)
 
-	infolist := make([]*loader.PackageInfo, 0, len(iprog.Imported))
-	for _, info := range iprog.Imported {
-		infolist = append(infolist, info)
-	}
-	for _, info := range iprog.Created {
-		infolist = append(infolist, info)
-	}
-	for _, info := range infolist {
-		for _, f := range info.Files {
-			lint.WalkNames(iprog.Fset, f, func(id *ast.Ident, thing interface{}) {
-				if !option.filter.byName(id.Name) {
+	var findings []*finding
+	var skipped int
+	if option.apply != "" {
+		entries, err := rename.ReadManifest(option.apply)
+		if err != nil {
+			return err
+		}
+		n := renamer.Apply(entries)
+		if verbose {
+			log.Printf("%d object(s) match the %d entries of %s", n, len(entries), option.apply)
+		}
+	} else {
+		// A file shared by a package and its test variant is walked once.
+		seen := make(map[string]bool)
+		seenPackages := make(map[string]bool)
+		defs := make(map[*packages.Package]*rename.DefIndex)
+		handle := func(pkg *packages.Package, id *ast.Ident, thing interface{}, spec *lint.Spec) {
+			if spec == nil || !option.filter.byName(id.Name) {
+				return
+			}
+			if !option.filter.byThing(thing) || !option.filter.byCategory(spec.Category) {
+				return
+			}
+
+			var obj types.Object
+			var api, exported bool
+			if _, ok := thing.(lint.PackageObj); ok {
+				// A package name is reported once per package, and an
+				// external test package is renamed along with the
+				// package it tests.
+				if seenPackages[pkg.PkgPath] || strings.HasSuffix(pkg.PkgPath, "_test") {
 					return
 				}
-				if !option.filter.byThing(thing) {
+				seenPackages[pkg.PkgPath] = true
+				api = renamer.IsAPIPackage(pkg.Types)
+				exported = api
+			} else {
+				if defs[pkg] == nil {
+					defs[pkg] = rename.NewDefIndex(pkg.TypesInfo)
+				}
+				obj = defs[pkg].DefOf(id)
+				if obj == nil {
 					return
 				}
-				if obj := info.Info.Defs[id]; obj != nil {
-					if spec := lint.Check(id); spec != nil {
-						if !option.filter.byCategory(spec.Category) {
-							return
-						}
-						if option.check {
-							pos := iprog.Fset.Position(id.Pos())
-							filename := filepath.Base(pos.String())
-							path := path.Join(info.Pkg.Path(), filename)
-							fmt.Fprintf(os.Stderr, "%s: %s %s should be %s\n", path, thing, id, spec.To)
+				api = renamer.IsAPI(obj)
+				exported = obj.Exported()
+			}
+
+			if option.check {
+				pos := fset.Position(id.Pos())
+				findings = append(findings, &finding{
+					Package:    pkg.PkgPath,
+					Line:       pos.Line,
+					Column:     pos.Column,
+					Name:       id.Name,
+					Thing:      fmt.Sprint(thing),
+					Category:   spec.Category.String(),
+					Suggestion: spec.To,
+					Exported:   exported,
+					obj:        obj,
+					filename:   pos.Filename,
+					offset:     pos.Offset,
+				})
+			}
+			if !option.check && !option.exported && api {
+				if verbose {
+					log.Printf("Skipping exported %s %s", thing, id.Name)
+				}
+				skipped++
+				return
+			}
+			if obj == nil {
+				renamer.RenamePackage(pkg.Types, *spec)
+			} else {
+				renamer.Rename(obj, *spec)
+			}
+		}
+
+		// Receivers are checked once the methods of their types have
+		// been walked in all the variants of their package.
+		receivers := make(map[string]*lint.Receivers)
+		var receiverPaths []string
+		receiverPkgs := make(map[*ast.Ident]*packages.Package)
+		receiverThings := make(map[*ast.Ident]lint.ReceiverObj)
+
+		for _, pkg := range pkgs {
+			for _, f := range pkg.Syntax {
+				filename := fset.File(f.Pos()).Name()
+				if seen[filename] {
+					continue
+				}
+				seen[filename] = true
+
+				lint.WalkNames(fset, f, pkg.TypesInfo, func(id *ast.Ident, thing interface{}) {
+					if recv, ok := thing.(lint.ReceiverObj); ok {
+						if receivers[pkg.PkgPath] == nil {
+							receivers[pkg.PkgPath] = lint.NewReceivers(pkg.PkgPath)
+							receiverPaths = append(receiverPaths, pkg.PkgPath)
 						}
-						renamer.Rename(obj, *spec)
+						receivers[pkg.PkgPath].Add(id, recv)
+						receiverPkgs[id] = pkg
+						receiverThings[id] = recv
+						return
 					}
-				}
-			})
+					handle(pkg, id, thing, lint.CheckThing(pkg.PkgPath, f, pkg.TypesInfo, id, thing))
+				})
+			}
+		}
+		for _, path := range receiverPaths {
+			for _, spec := range receivers[path].Check() {
+				handle(receiverPkgs[spec.Id], spec.Id, receiverThings[spec.Id], spec)
+			}
+		}
+	}
+
+	if option.check {
+		root, err := rootDir(pkgs)
+		if err != nil {
+			return err
+		}
+		// Receivers are checked after the other names, so order the
+		// findings by position.
+		sort.SliceStable(findings, func(i, j int) bool {
+			a, b := findings[i], findings[j]
+			if a.filename != b.filename {
+				return a.filename < b.filename
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+		// The sites count columns in code points, for which the
+		// source lines are needed.
+		sources := make(map[string][]byte)
+		source := func(filename string) []byte {
+			if src, ok := sources[filename]; ok {
+				return src
+			}
+			src, _ := ioutil.ReadFile(filename)
+			sources[filename] = src
+			return src
+		}
+		// Find the renames that would be refused, and the objects
+		// renamed together, before reporting the sites to rewrite.
+		renamer.Check()
+		for _, f := range findings {
+			f.File = relPath(root, f.filename)
+			pos := token.Position{Filename: f.filename, Offset: f.offset, Line: f.Line, Column: f.Column}
+			f.def = newSite(f.File, pos, source(f.filename))
+			var positions []token.Position
+			if f.obj != nil {
+				f.Refused = !renamer.Pending(f.obj)
+				positions = renamer.GroupOccurrences(f.obj)
+			} else {
+				f.Refused = !renamer.PendingPackage(f.Package)
+				positions = renamer.PackageOccurrences(f.Package)
+			}
+			for _, pos := range positions {
+				f.sites = append(f.sites, newSite(relPath(root, pos.Filename), pos, source(pos.Filename)))
+			}
+			// Every occurrence in the group but the declaration is a
+			// reference.
+			f.References = len(f.sites) - 1
+		}
+
+		w := os.Stdout
+		if option.format == "text" {
+			w = os.Stderr
+		}
+		if err := formats[option.format](w, findings); err != nil {
+			return err
+		}
+	}
+
+	if skipped > 0 {
+		log.Printf("Skipped %d exported name(s); use -exported to rename them.", skipped)
+	}
+
+	err = renamer.Update()
+	if option.exported && !option.check && !verifyFailed {
+		if changes := renamer.APIChanges(); len(changes) > 0 {
+			if option.aliases {
+				fmt.Fprintln(os.Stderr, "Changes to the exported API (old names kept as deprecated aliases where possible):")
+			} else {
+				fmt.Fprintln(os.Stderr, "Breaking changes to the exported API:")
+			}
+			for _, c := range changes {
+				fmt.Fprintf(os.Stderr, "\t%s -> %s\n", c.Old, c.New)
+			}
+		}
+	}
+	if option.manifest != "" && !verifyFailed {
+		if werr := writeManifest(option.manifest, renamer.Manifest()); werr != nil && err == nil {
+			err = werr
 		}
 	}
+	if option.patch != "" && !option.check && !option.inplace && !verifyFailed {
+		if werr := ioutil.WriteFile(option.patch, patch.Bytes(), 0644); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
 
-	return renamer.Update()
+func writeManifest(filename string, entries []rename.ManifestEntry) error {
+	var buf bytes.Buffer
+	if err := rename.WriteManifest(&buf, entries); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
 }
 
 var (
-	flagInplace = flag.Bool("inplace", false, "edit in-place")
-	flagCheck   = flag.Bool("check", false, "perform lint check")
-	flagVerbose = flag.Bool("verbose", false, "show verbose messages")
-	flagFilter  = flag.String("filter", "", "specify filter conditions by comma-separated string")
-	flagRegex   = flag.String("regex", "", "Specify regex for additional filtering")
+	flagInplace  = flag.Bool("inplace", false, "edit in-place")
+	flagCheck    = flag.Bool("check", false, "perform lint check")
+	flagExported = flag.Bool("exported", false, "also rename exported API, reporting the breaking changes")
+	flagAliases  = flag.Bool("aliases", false, "like -exported, but keep the old names as deprecated aliases in "+rename.AliasFile)
+	flagManifest = flag.String("manifest", "", "write the renames of exported API to the specified file, for use with -apply")
+	flagApply    = flag.String("apply", "", "instead of checking names, apply the renames in the specified manifest to uses in the given packages")
+	flagTags     = flag.String("tags", "", "comma-separated encodings ("+strings.Join(rename.Encodings, ", ")+") for which to add struct tags keeping the keys of renamed fields")
+	flagStrings  = flag.Bool("strings", false, "rewrite references to renamed names in reflection calls and templates")
+	flagComments = flag.Bool("comments", false, "rewrite renamed names in all comments of their packages, not only in doc comments and links")
+	flagVerify   = flag.Bool("verify", true, "type-check the renamed program before writing")
+	flagPatch    = flag.String("patch", "", "write a single patch for all changes to the specified file")
+	flagFormat   = flag.String("format", "text", "output format of -check: text, json or sarif")
+	flagConfig   = flag.String("config", "", "configuration file (default: "+config.FileName+" in the current directory or above)")
+	flagVerbose  = flag.Bool("verbose", false, "show verbose messages")
+	flagFilter   = flag.String("filter", "", "specify filter conditions by comma-separated string")
+	flagRegex    = flag.String("regex", "", "Specify regex for additional filtering")
 )
 
 func init() {
@@ -171,8 +489,9 @@ func init() {
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "A valid filter item is an element of the following sets:\n")
-		fmt.Fprintf(os.Stderr, "  {caps, underscore}\n")
-		fmt.Fprintf(os.Stderr, "  {const, var, type, struct field, func}\n")
+		fmt.Fprintf(os.Stderr, "  {caps, underscore, stutter, recv, error}\n")
+		fmt.Fprintf(os.Stderr, "  {const, var, type, type parameter, struct field, func, label}\n")
+		fmt.Fprintf(os.Stderr, "  {receiver, type switch var, select var, func literal}, kinds of var\n")
 	}
 }
 
@@ -188,6 +507,12 @@ func parseFilter(str, rx string) (*Filter, error) {
 			filter.category |= Caps
 		case "underscore":
 			filter.category |= Underscore
+		case "stutter":
+			filter.category |= Stutter
+		case "recv":
+			filter.category |= Recv
+		case "error":
+			filter.category |= ErrorName
 		case "const":
 			filter.thing |= Const
 		case "var":
@@ -198,6 +523,18 @@ func parseFilter(str, rx string) (*Filter, error) {
 			filter.thing |= StructField
 		case "func":
 			filter.thing |= Func
+		case "type parameter":
+			filter.thing |= TypeParam
+		case "receiver":
+			filter.thing |= Receiver
+		case "label":
+			filter.thing |= Label
+		case "type switch var":
+			filter.thing |= TypeSwitchVar
+		case "select var":
+			filter.thing |= SelectVar
+		case "func literal":
+			filter.thing |= FuncLit
 		default:
 			return nil, fmt.Errorf("Unknown filter: %s", e)
 		}
@@ -212,6 +549,27 @@ func parseFilter(str, rx string) (*Filter, error) {
 	return &filter, nil
 }
 
+func parseTags(str string) ([]string, error) {
+	var tags []string
+	for _, e := range strings.Split(str, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		known := false
+		for _, enc := range rename.Encodings {
+			if e == enc {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("-tags: unsupported encoding: %s", e)
+		}
+		tags = append(tags, e)
+	}
+	return tags, nil
+}
+
 func ParseOption() *Option {
 	flag.Parse()
 
@@ -219,13 +577,34 @@ func ParseOption() *Option {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if formats[*flagFormat] == nil {
+		log.Fatalf("Unknown format: %s", *flagFormat)
+	}
+	tags, err := parseTags(*flagTags)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *flagApply != "" && *flagCheck {
+		log.Fatal("-apply cannot be used with -check")
+	}
 
 	return &Option{
-		inplace: *flagInplace,
-		check:   *flagCheck,
-		verbose: *flagVerbose,
-		filter:  *filter,
-		args:    flag.Args(),
+		inplace:  *flagInplace,
+		check:    *flagCheck,
+		exported: *flagExported || *flagAliases,
+		aliases:  *flagAliases,
+		manifest: *flagManifest,
+		apply:    *flagApply,
+		tags:     tags,
+		strings:  *flagStrings,
+		comments: *flagComments,
+		verify:   *flagVerify,
+		patch:    *flagPatch,
+		format:   *flagFormat,
+		config:   *flagConfig,
+		verbose:  *flagVerbose,
+		filter:   *filter,
+		args:     flag.Args(),
 	}
 }
 
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestRootDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "fixname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	sub := filepath.Join(root, "m", "p")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	// The repository wins over the main module.
+	pkgs := []*packages.Package{{Module: &packages.Module{Main: true, Dir: filepath.Join(root, "m")}}}
+	if dir, err := rootDir(pkgs); err != nil || dir != root {
+		t.Errorf("expected: %s, got: %s, %v", root, dir, err)
+	}
+}
+
+// writeFiles writes files, given by slash-separated path relative to
+// root, and their contents.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyFailure(t *testing.T) {
+	root, err := ioutil.TempDir("", "fixname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// The manifest renames a function of lib, which is not loaded, so
+	// the renamed use in app no longer type-checks.
+	const app = `package app
+
+import "example.com/m/lib"
+
+var n = lib.Helper()
+`
+	writeFiles(t, root, map[string]string{
+		"go.mod":      "module example.com/m\n\ngo 1.18\n",
+		"lib/lib.go":  "package lib\n\nfunc Helper() int { return 0 }\n",
+		"app/app.go":  app,
+		"rename.json": `[{"package": "example.com/m/lib", "old": "Helper", "new": "Help", "kind": "func"}]`,
+	})
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := parseFilter("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testData := []struct {
+		name   string
+		option Option
+	}{
+		{"inplace", Option{inplace: true}},
+		{"patch", Option{patch: "out.patch", manifest: "out.json"}},
+	}
+	for _, tt := range testData {
+		tt.option.apply = "rename.json"
+		tt.option.verify = true
+		tt.option.format = "text"
+		tt.option.filter = *filter
+		tt.option.args = []string{"./app"}
+		err := Main(&tt.option)
+		if err == nil || !strings.Contains(err.Error(), "fails to type-check") {
+			t.Errorf("%s: expected a verification error, got: %v", tt.name, err)
+		}
+		if content, err := ioutil.ReadFile(filepath.Join(root, "app", "app.go")); err != nil || string(content) != app {
+			t.Errorf("%s: app.go was changed: %s, %v", tt.name, content, err)
+		}
+		for _, name := range []string{"out.patch", "out.json"} {
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("%s: %s was written", tt.name, name)
+			}
+		}
+	}
+}
+
+func TestLoadPackages(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	// Flags such as -mod=mod are not allowed in workspace mode.
+	t.Setenv("GOFLAGS", "")
+
+	lib := filepath.Join(wd, "testdata", "modules", "lib", "lib.go")
+	testData := []struct {
+		name     string
+		dir      string
+		expected []string
+	}{
+		{
+			name: "replace",
+			dir:  "replace",
+			expected: []string{
+				"example.com/replace",
+				"example.com/replace [example.com/replace.test]",
+				"example.com/replace/sub",
+				"example.com/replace_test [example.com/replace.test]",
+			},
+		},
+		{
+			name:     "workspace",
+			dir:      filepath.Join("work", "app"),
+			expected: []string{"example.com/app"},
+		},
+	}
+	for _, tt := range testData {
+		if err := os.Chdir(filepath.Join(wd, "testdata", "modules", tt.dir)); err != nil {
+			t.Fatal(err)
+		}
+		_, pkgs, err := loadPackages([]string{"./..."}, nil, false)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var ids []string
+		for _, pkg := range pkgs {
+			ids = append(ids, pkg.ID)
+			if pkg.TypesInfo == nil || len(pkg.Syntax) == 0 {
+				t.Errorf("%s: %s has no type info", tt.name, pkg.ID)
+			}
+		}
+		if strings.Join(ids, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s: expected: %v, got: %v", tt.name, tt.expected, ids)
+		}
+		// lib is resolved to the sibling module on disk.
+		dep := pkgs[0].Imports["example.com/lib"]
+		if dep == nil || len(dep.GoFiles) != 1 || dep.GoFiles[0] != lib {
+			t.Errorf("%s: example.com/lib not loaded from %s", tt.name, lib)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AliasFile is the name of the file that SetAliases adds to each package
+// with renamed exported identifiers. If it already exists, a numbered
+// variant such as fixname_aliases_2.go is used.
+const AliasFile = "fixname_aliases.go"
+
+// SetAliases makes Update keep the old names of renamed exported types,
+// functions, constants and variables as deprecated aliases, so that
+// importers outside the loaded program keep compiling.
+func (r *Renamer) SetAliases(aliases bool) {
+	r.aliases = aliases
+}
+
+// aliasSet accumulates the declarations of the alias file of a package.
+type aliasSet struct {
+	dir     string
+	pkgName string
+	decls   []string
+	imports map[string]*types.Package // by local name
+}
+
+// aliasFiles returns the content of the alias file of every package
+// with renamed exported API, by filename. It must be called once the
+// identifiers have been renamed in the syntax trees.
+func (r *Renamer) aliasFiles() map[string][]byte {
+	var keys []objKey
+	for key := range r.objsToUpdate {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pos.Filename != keys[j].pos.Filename {
+			return keys[i].pos.Filename < keys[j].pos.Filename
+		}
+		return keys[i].pos.Offset < keys[j].pos.Offset
+	})
+
+	sets := make(map[string]*aliasSet)
+	var dirs []string
+	for _, key := range keys {
+		objs := r.objectsOf(key)
+		if len(objs) == 0 || r.packageOf(objs[0]) == nil || !r.IsAPI(objs[0]) {
+			continue
+		}
+		obj, to := objs[0], r.objsToUpdate[key].To
+		dir := filepath.Dir(key.pos.Filename)
+		set := sets[dir]
+		if set == nil {
+			// The file joins the package under its new name if the
+			// package is renamed too.
+			pkgName := obj.Pkg().Name()
+			if spec, ok := r.packages[obj.Pkg().Path()]; ok {
+				pkgName = spec.To
+			}
+			set = &aliasSet{dir: dir, pkgName: pkgName, imports: make(map[string]*types.Package)}
+			sets[dir] = set
+			dirs = append(dirs, dir)
+		}
+		if err := set.add(r, obj, to); err != nil {
+			log.Printf("%s: not adding an alias: %v", key.pos, err)
+		}
+	}
+
+	files := make(map[string][]byte)
+	for _, dir := range dirs {
+		set := sets[dir]
+		if len(set.decls) == 0 {
+			continue
+		}
+		content, err := set.format()
+		if err != nil {
+			log.Printf("failed to generate aliases for package %s: %v", set.pkgName, err)
+			continue
+		}
+		files[aliasFilename(dir)] = content
+	}
+	return files
+}
+
+// aliasFilename returns the name of a file in dir that does not exist
+// yet for the aliases.
+func aliasFilename(dir string) string {
+	ext := filepath.Ext(AliasFile)
+	base := strings.TrimSuffix(AliasFile, ext)
+	filename := filepath.Join(dir, AliasFile)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return filename
+		}
+		filename = filepath.Join(dir, fmt.Sprintf("%s_%d%s", base, i, ext))
+	}
+}
+
+func (s *aliasSet) add(r *Renamer, obj types.Object, to string) error {
+	doc := fmt.Sprintf("// Deprecated: Use %s instead.\n", to)
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			return fmt.Errorf("%s is generic", obj.Name())
+		}
+		s.decls = append(s.decls, fmt.Sprintf("%stype %s = %s\n", doc, obj.Name(), to))
+	case *types.Const:
+		s.decls = append(s.decls, fmt.Sprintf("%sconst %s = %s\n", doc, obj.Name(), to))
+	case *types.Var:
+		if obj.IsField() {
+			return fmt.Errorf("%s is a field", obj.Name())
+		}
+		s.decls = append(s.decls, fmt.Sprintf(
+			"// %s is a copy of the initial value of %s; assignments to\n// either are not seen through the other.\n//\n%svar %s = %s\n",
+			obj.Name(), to, doc, obj.Name(), to))
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return fmt.Errorf("%s is a method", obj.Name())
+		}
+		decl := r.funcDecl(obj)
+		if decl == nil {
+			return fmt.Errorf("declaration of %s not found", obj.Name())
+		}
+		wrapper, err := s.wrapper(r, r.packageOf(obj).TypesInfo, decl, to)
+		if err != nil {
+			return err
+		}
+		s.decls = append(s.decls, doc+wrapper)
+	default:
+		return fmt.Errorf("unexpected %s", describe(obj))
+	}
+	return nil
+}
+
+// funcDecl returns the declaration of the package-level function obj.
+func (r *Renamer) funcDecl(obj *types.Func) *ast.FuncDecl {
+	pkg := r.packageOf(obj)
+	if pkg == nil {
+		return nil
+	}
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Name.Pos() == obj.Pos() {
+				return fd
+			}
+		}
+	}
+	return nil
+}
+
+// wrapper returns a function with the old name of the function declared
+// by decl, which has already been renamed to to, that forwards to it.
+func (s *aliasSet) wrapper(r *Renamer, info *types.Info, decl *ast.FuncDecl, to string) (string, error) {
+	if err := s.addImports(info, decl.Type); err != nil {
+		return "", err
+	}
+	from := info.Defs[decl.Name].Name()
+
+	used := make(map[string]bool)
+	ast.Inspect(decl.Type, func(node ast.Node) bool {
+		if id, ok := node.(*ast.Ident); ok {
+			used[id.Name] = true
+		}
+		return true
+	})
+	var n int
+	fresh := func() string {
+		for {
+			n++
+			name := fmt.Sprintf("p%d", n)
+			if !used[name] {
+				used[name] = true
+				return name
+			}
+		}
+	}
+
+	var tparams, targs []string
+	if decl.Type.TypeParams != nil {
+		for _, field := range decl.Type.TypeParams.List {
+			constraint, err := r.exprString(field.Type)
+			if err != nil {
+				return "", err
+			}
+			var names []string
+			for _, id := range field.Names {
+				names = append(names, id.Name)
+			}
+			targs = append(targs, names...)
+			tparams = append(tparams, strings.Join(names, ", ")+" "+constraint)
+		}
+	}
+
+	var params, args []string
+	for _, field := range decl.Type.Params.List {
+		typ, err := r.exprString(field.Type)
+		if err != nil {
+			return "", err
+		}
+		_, variadic := field.Type.(*ast.Ellipsis)
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{{Name: "_"}}
+		}
+		for _, id := range names {
+			name := id.Name
+			if name == "_" {
+				name = fresh()
+			}
+			params = append(params, name+" "+typ)
+			if variadic {
+				name += "..."
+			}
+			args = append(args, name)
+		}
+	}
+
+	var results []string
+	if decl.Type.Results != nil {
+		for _, field := range decl.Type.Results.List {
+			typ, err := r.exprString(field.Type)
+			if err != nil {
+				return "", err
+			}
+			for i := 0; i < len(field.Names) || i == 0; i++ {
+				results = append(results, typ)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s", from)
+	if len(tparams) > 0 {
+		fmt.Fprintf(&buf, "[%s]", strings.Join(tparams, ", "))
+	}
+	fmt.Fprintf(&buf, "(%s)", strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&buf, " %s", results[0])
+	default:
+		fmt.Fprintf(&buf, " (%s)", strings.Join(results, ", "))
+	}
+	buf.WriteString(" {\n\t")
+	if len(results) > 0 {
+		buf.WriteString("return ")
+	}
+	buf.WriteString(to)
+	if len(targs) > 0 {
+		fmt.Fprintf(&buf, "[%s]", strings.Join(targs, ", "))
+	}
+	fmt.Fprintf(&buf, "(%s)\n}\n", strings.Join(args, ", "))
+	return buf.String(), nil
+}
+
+// addImports records the imports that the qualified identifiers in node
+// refer to, under the same local names.
+func (s *aliasSet) addImports(info *types.Info, node ast.Node) error {
+	var err error
+	ast.Inspect(node, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		id, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pkgName, ok := info.Uses[id].(*types.PkgName)
+		if !ok {
+			return true
+		}
+		imported := pkgName.Imported()
+		if p, ok := s.imports[id.Name]; ok && p.Path() != imported.Path() {
+			err = fmt.Errorf("%s refers to both %q and %q", id.Name, p.Path(), imported.Path())
+			return false
+		}
+		s.imports[id.Name] = imported
+		return true
+	})
+	return err
+}
+
+func (r *Renamer) exprString(expr ast.Expr) (string, error) {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, r.fset, expr); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *aliasSet) format() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// This file was generated by go-fixname to keep the old names of\n")
+	buf.WriteString("// renamed identifiers working. Remove it once importers have migrated.\n\n")
+	fmt.Fprintf(&buf, "package %s\n", s.pkgName)
+
+	if len(s.imports) > 0 {
+		var names []string
+		for name := range s.imports {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return s.imports[names[i]].Path() < s.imports[names[j]].Path()
+		})
+		buf.WriteString("\nimport (\n")
+		for _, name := range names {
+			imported := s.imports[name]
+			if name == imported.Name() {
+				fmt.Fprintf(&buf, "\t%q\n", imported.Path())
+			} else {
+				fmt.Fprintf(&buf, "\t%s %q\n", name, imported.Path())
+			}
+		}
+		buf.WriteString(")\n")
+	}
+
+	for _, decl := range s.decls {
+		buf.WriteString("\n")
+		buf.WriteString(decl)
+	}
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,100 @@
+package rename
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestAliases(t *testing.T) {
+	fset, pkg := loadTestPackage(t, `package p
+
+type User_Info struct{ name string }
+
+const Max_Len = 10
+
+var Default_User = User_Info{}
+
+func Get_User(id int, _ string, opts ...bool) (*User_Info, error) { return nil, nil }
+
+func Map_Keys[K comparable, V any](m map[K]V) []K { return nil }
+
+func Reset_All() {}
+
+func (u *User_Info) Get_Name() string { return u.name }
+
+func helper_func() {}
+`)
+	r := New(fset, []*packages.Package{pkg})
+	r.SetAliases(true)
+	for _, name := range []string{"User_Info", "Max_Len", "Default_User", "Get_User", "Map_Keys", "Reset_All", "Get_Name", "helper_func"} {
+		spec := lint.Check(&ast.Ident{Name: name})
+		r.Rename(lookupDef(pkg, name), *spec)
+	}
+
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `// This file was generated by go-fixname to keep the old names of
+// renamed identifiers working. Remove it once importers have migrated.
+
+package p
+
+// Deprecated: Use UserInfo instead.
+type User_Info = UserInfo
+
+// Deprecated: Use MaxLen instead.
+const Max_Len = MaxLen
+
+// Default_User is a copy of the initial value of DefaultUser; assignments to
+// either are not seen through the other.
+//
+// Deprecated: Use DefaultUser instead.
+var Default_User = DefaultUser
+
+// Deprecated: Use GetUser instead.
+func Get_User(id int, p1 string, opts ...bool) (*UserInfo, error) {
+	return GetUser(id, p1, opts...)
+}
+
+// Deprecated: Use MapKeys instead.
+func Map_Keys[K comparable, V any](m map[K]V) []K {
+	return MapKeys[K, V](m)
+}
+
+// Deprecated: Use ResetAll instead.
+func Reset_All() {
+	ResetAll()
+}
+`
+	if actual := contents["/src/p/"+AliasFile]; actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestAliasesRenamedPackage(t *testing.T) {
+	fset, pkgs := loadTestPackages(t, [][2]string{{"example.com/my_util", `package my_util
+
+func Get_Value() int { return 1 }
+`}})
+	util := pkgs[0]
+	r := New(fset, pkgs)
+	r.SetAliases(true)
+	r.RenamePackage(util.Types, *lint.CheckPackageName(util.Syntax[0].Name))
+	r.Rename(lookupDef(util, "Get_Value"), *lint.Check(&ast.Ident{Name: "Get_Value"}))
+
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := contents["/src/example.com/my_util/"+AliasFile]
+	if !strings.Contains(actual, "\npackage myutil\n") {
+		t.Errorf("expected the new package name in:\n%s", actual)
+	}
+}
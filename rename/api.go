@@ -0,0 +1,135 @@
+package rename
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// An APIChange is a rename of part of the exported API of a package,
+// given as qualified names such as "example.com/p.T.Name".
+type APIChange struct {
+	Old, New string
+}
+
+// IsAPI reports whether renaming obj may break importers of its package
+// outside the loaded program: obj is an exported package-level object,
+// or an exported field or method of a package-level type, declared
+// outside test files in a package other than main.
+func (r *Renamer) IsAPI(obj types.Object) bool {
+	if !obj.Exported() || obj.Pkg() == nil || obj.Pkg().Name() == "main" {
+		return false
+	}
+	if strings.HasSuffix(r.position(obj.Pos()).Filename, "_test.go") {
+		return false
+	}
+	scope := obj.Pkg().Scope()
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !obj.IsField() {
+			return obj.Parent() == scope
+		}
+	case *types.Func:
+		recv := obj.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return obj.Parent() == scope
+		}
+		if !types.IsInterface(recv.Type()) {
+			// concrete methods are declared at package level
+			return true
+		}
+	default:
+		return obj.Parent() == scope
+	}
+	// A field or interface method; those of types local to a function
+	// are not reachable from other packages.
+	s := scope.Innermost(obj.Pos())
+	return s == nil || s.Parent() == scope
+}
+
+// APIChanges returns the renames of exported API in sorted order, giving
+// fields and methods under the new name of their type. Called after
+// Update or Check, it omits the renames refused due to conflicts.
+func (r *Renamer) APIChanges() []APIChange {
+	var changes []APIChange
+	for _, e := range r.Manifest() {
+		if e.Kind == "package" {
+			changes = append(changes, APIChange{
+				Old: e.Package + " (package " + e.Old + ")",
+				New: e.Package + " (package " + e.New + ")",
+			})
+			continue
+		}
+		from, to := e.Package+".", e.Package+"."
+		if e.Recv != "" {
+			from += e.Recv + "."
+			to += r.newOwner(e.Package, e.Recv) + "."
+		}
+		changes = append(changes, APIChange{
+			Old: from + e.Old,
+			New: to + e.New,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Old < changes[j].Old
+	})
+	return changes
+}
+
+// newOwner returns the name that the type named recv, which declares a
+// field or method of the package path, will have.
+func (r *Renamer) newOwner(path, recv string) string {
+	for _, pkg := range r.pkgs {
+		if pkg.PkgPath != path {
+			continue
+		}
+		if tn, ok := pkg.Types.Scope().Lookup(recv).(*types.TypeName); ok {
+			return r.nameOf(tn)
+		}
+	}
+	return recv
+}
+
+// owner returns the name of the package-level type that declares the
+// field or method obj, or "" if obj is neither or there is no such type.
+func owner(obj types.Object) string {
+	scope := obj.Pkg().Scope()
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !obj.IsField() {
+			return ""
+		}
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			if st, ok := tn.Type().Underlying().(*types.Struct); ok {
+				for i := 0; i < st.NumFields(); i++ {
+					if st.Field(i) == obj {
+						return name
+					}
+				}
+			}
+		}
+	case *types.Func:
+		recv := obj.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return ""
+		}
+		t := recv.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if named, ok := t.(*types.Named); ok {
+			return named.Obj().Name()
+		}
+		// an interface type literal; find the type declared with it
+		for _, name := range scope.Names() {
+			if tn, ok := scope.Lookup(name).(*types.TypeName); ok && !tn.IsAlias() && tn.Type().Underlying() == t {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package rename
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestIsAPI(t *testing.T) {
+	fset, pkg := loadTestPackage(t, `package p
+const Max_Len = 1
+var user_id int
+type User_Info struct {
+	User_Id int
+	user_name string
+}
+func (u *User_Info) Get_Name() string {
+	type Local_T struct{ Field_X int }
+	Local_V := Local_T{}
+	_ = Local_V
+	return u.user_name
+}
+type Getter_I interface {
+	Get_Value() int
+}
+`)
+	r := New(fset, []*packages.Package{pkg})
+
+	testData := map[string]bool{
+		"Max_Len":   true,
+		"user_id":   false,
+		"User_Info": true,
+		"User_Id":   true,
+		"user_name": false,
+		"Get_Name":  true,
+		"Local_T":   false,
+		"Field_X":   false,
+		"Local_V":   false,
+		"Get_Value": true,
+	}
+	for name, expected := range testData {
+		obj := lookupDef(pkg, name)
+		if obj == nil {
+			t.Fatalf("%s not found", name)
+		}
+		if actual := r.IsAPI(obj); actual != expected {
+			t.Errorf("name: %s, expected: %v, got: %v", name, expected, actual)
+		}
+	}
+
+	for _, name := range []string{"Max_Len", "user_id", "User_Info", "User_Id", "Get_Name", "Get_Value"} {
+		spec := lint.Check(&ast.Ident{Name: name})
+		r.Rename(lookupDef(pkg, name), *spec)
+	}
+	expected := []APIChange{
+		{Old: "p.Getter_I.Get_Value", New: "p.Getter_I.GetValue"},
+		{Old: "p.Max_Len", New: "p.MaxLen"},
+		{Old: "p.User_Info", New: "p.UserInfo"},
+		{Old: "p.User_Info.Get_Name", New: "p.UserInfo.GetName"},
+		{Old: "p.User_Info.User_Id", New: "p.UserInfo.UserID"},
+	}
+	if actual := r.APIChanges(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
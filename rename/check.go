@@ -0,0 +1,469 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+)
+
+// A Conflict describes a rename that was refused because applying it
+// would make the program ill-formed or change its meaning.
+type Conflict struct {
+	Obj types.Object
+	To  string
+	Pos token.Position
+	Msg string
+}
+
+func (c *Conflict) Error() string {
+	return fmt.Sprintf("%s: %s", c.Pos, c.Msg)
+}
+
+// Check refuses every pending rename that would conflict with an
+// existing declaration or with another pending rename, and returns the
+// reasons, including those of earlier checks. Update performs the same
+// check before rewriting anything.
+func (r *Renamer) Check() []*Conflict {
+	return r.checkConflicts()
+}
+
+// Pending reports whether a rename of obj is pending, that is, has been
+// requested and not refused so far.
+func (r *Renamer) Pending(obj types.Object) bool {
+	_, ok := r.objsToUpdate[r.key(obj)]
+	return ok
+}
+
+// checkConflicts drops every rename that conflicts with an existing
+// declaration or with another pending rename, and returns the reasons,
+// including those of earlier calls. Refusing a rename may in turn
+// invalidate others, so it iterates until the remaining set is
+// consistent.
+func (r *Renamer) checkConflicts() []*Conflict {
+	conflicts := r.expandGroups()
+	r.expandPackages()
+	defer func() { r.names = nil }()
+	for {
+		// The names of objects change as renames are refused.
+		r.names = nil
+		keys := make([]objKey, 0, len(r.objsToUpdate))
+		for key := range r.objsToUpdate {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].pos.Filename != keys[j].pos.Filename {
+				return keys[i].pos.Filename < keys[j].pos.Filename
+			}
+			return keys[i].pos.Offset < keys[j].pos.Offset
+		})
+
+		var found []*Conflict
+		for _, key := range keys {
+			// Check the object in every package variant, since a
+			// test file may add declarations the others lack.
+			for _, obj := range r.objectsOf(key) {
+				if c := r.check(obj, r.objsToUpdate[key].To); c != nil {
+					found = append(found, c)
+					break
+				}
+			}
+		}
+		if len(found) == 0 {
+			break
+		}
+		for _, c := range found {
+			delete(r.objsToUpdate, r.key(c.Obj))
+		}
+		conflicts = append(conflicts, found...)
+		// A group is renamed together or not at all.
+		for _, c := range found {
+			for _, k := range r.groups[r.key(c.Obj)] {
+				if _, ok := r.objsToUpdate[k]; !ok {
+					continue
+				}
+				obj := r.objectsOf(k)[0]
+				conflicts = append(conflicts, r.conflict(obj, r.objsToUpdate[k].To,
+					"is refused together with %s declared at %s", describe(c.Obj), c.Pos))
+				delete(r.objsToUpdate, k)
+			}
+		}
+	}
+	r.dropRefusedPackages()
+	r.conflicts = append(r.conflicts, conflicts...)
+	return r.conflicts
+}
+
+func (r *Renamer) check(from types.Object, to string) *Conflict {
+	if !token.IsIdentifier(to) {
+		return r.conflict(from, to, "%q is not a valid identifier", to)
+	}
+
+	switch obj := from.(type) {
+	case *types.Var:
+		if obj.IsField() {
+			return r.checkField(obj, to)
+		}
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return r.checkMethod(obj, to)
+		}
+	case *types.Label:
+		// Labels have their own namespace, the function body.
+		if prev := r.lookup(obj.Parent(), to, from, token.NoPos); prev != nil {
+			return r.conflict(from, to, "conflicts with %s declared at %s",
+				describe(prev), r.position(prev.Pos()))
+		}
+		return nil
+	}
+	return r.checkInLexicalScope(from, to)
+}
+
+// checkInLexicalScope checks that renaming from to to neither collides
+// with a declaration in the same block, nor shadows a reference to an
+// object declared in an enclosing block, nor gets shadowed by a
+// declaration in a nested block.
+func (r *Renamer) checkInLexicalScope(from types.Object, to string) *Conflict {
+	pkg := r.packageOf(from)
+	b := from.Parent()
+	if pkg == nil || b == nil {
+		return nil
+	}
+	info := pkg.TypesInfo
+	pkgScope := pkg.Types.Scope()
+
+	// Same block.
+	if prev := r.lookup(b, to, from, token.NoPos); prev != nil {
+		return r.conflict(from, to, "conflicts with %s declared at %s",
+			describe(prev), r.position(prev.Pos()))
+	}
+
+	// Package-level declarations and imports share a namespace.
+	if b == pkgScope {
+		for _, f := range pkg.Syntax {
+			if prev := r.lookup(info.Scopes[f], to, from, token.NoPos); prev != nil {
+				return r.conflict(from, to, "conflicts with %s imported at %s",
+					describe(prev), r.position(prev.Pos()))
+			}
+		}
+		if token.IsExported(to) {
+			if c := r.checkDotImports(from, to); c != nil {
+				return c
+			}
+		}
+	} else if b.Parent() == pkgScope {
+		if prev := r.lookup(pkgScope, to, from, token.NoPos); prev != nil {
+			return r.conflict(from, to, "conflicts with %s declared at %s",
+				describe(prev), r.position(prev.Pos()))
+		}
+	}
+
+	// Super-blocks: a reference to an object of the new name declared
+	// in an enclosing block must not be shadowed.
+	local := b != pkgScope && b.Parent() != pkgScope
+	for _, id := range r.usesNamed(pkg, to) {
+		obj := info.Uses[id]
+		if r.same(obj, from) || obj.Parent() == nil || obj.Parent() == b {
+			continue
+		}
+		if !encloses(obj.Parent(), b) {
+			continue
+		}
+		if b != pkgScope && !b.Contains(id.Pos()) {
+			continue
+		}
+		if local && id.Pos() < from.Pos() {
+			continue
+		}
+		return r.conflict(from, to, "would shadow the reference at %s to %s",
+			r.position(id.Pos()), describe(obj))
+	}
+
+	// Sub-blocks: a reference to from must not be captured by a
+	// declaration of the new name in a nested block.
+	for _, id := range r.usesIn(pkg).uses[r.key(from)] {
+		for s := pkgScope.Innermost(id.Pos()); s != nil && s != b && s != pkgScope; s = s.Parent() {
+			if prev := r.lookup(s, to, from, id.Pos()); prev != nil {
+				return r.conflict(from, to, "reference at %s would be shadowed by %s declared at %s",
+					r.position(id.Pos()), describe(prev), r.position(prev.Pos()))
+			}
+		}
+	}
+	return nil
+}
+
+// checkDotImports checks that the package-level object from, renamed to
+// the exported name to, does not collide with a declaration of an
+// initial package that dot-imports it, in that package or in the file
+// scope of the importing file, and that no reference to from in that
+// file would be shadowed by a declaration in a nested block.
+func (r *Renamer) checkDotImports(from types.Object, to string) *Conflict {
+	for _, pkg := range r.pkgs {
+		for _, f := range pkg.Syntax {
+			spec := dotImport(f, from.Pkg().Path())
+			if spec == nil {
+				continue
+			}
+			fileScope := pkg.TypesInfo.Scopes[f]
+			for _, s := range []*types.Scope{pkg.Types.Scope(), fileScope} {
+				if prev := r.lookup(s, to, from, token.NoPos); prev != nil {
+					return r.conflict(from, to, "dot-imported at %s conflicts with %s declared at %s",
+						r.position(spec.Pos()), describe(prev), r.position(prev.Pos()))
+				}
+			}
+			for _, id := range r.usesIn(pkg).uses[r.key(from)] {
+				if id.Pos() < f.Pos() || id.Pos() > f.End() {
+					continue
+				}
+				for s := fileScope.Innermost(id.Pos()); s != nil && s != fileScope; s = s.Parent() {
+					if prev := r.lookup(s, to, from, id.Pos()); prev != nil {
+						return r.conflict(from, to, "reference at %s would be shadowed by %s declared at %s",
+							r.position(id.Pos()), describe(prev), r.position(prev.Pos()))
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dotImport returns the import of the package with the given path into
+// the scope of f, or nil if f has none.
+func dotImport(f *ast.File, path string) *ast.ImportSpec {
+	for _, spec := range f.Imports {
+		if spec.Name == nil || spec.Name.Name != "." {
+			continue
+		}
+		if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == path {
+			return spec
+		}
+	}
+	return nil
+}
+
+// checkField checks that the renamed field does not collide with
+// another field or method of its struct, and that no selector
+// expression referring to it would resolve to something else.
+func (r *Renamer) checkField(from *types.Var, to string) *Conflict {
+	pkg := r.packageOf(from)
+	if pkg == nil {
+		// a field of a dependency renamed by Apply
+		return r.checkSelections(from, to)
+	}
+
+	if st := r.structOf(from); st != nil {
+		for i := 0; i < st.NumFields(); i++ {
+			if f := st.Field(i); !r.same(f, from) && r.nameOf(f) == to {
+				return r.conflict(from, to, "conflicts with %s declared at %s",
+					describe(f), r.position(f.Pos()))
+			}
+		}
+		for _, named := range r.namedStructsOf(st) {
+			if m := r.methodNamed(named, to, from); m != nil {
+				return r.conflict(from, to, "conflicts with %s declared at %s",
+					describe(m), r.position(m.Pos()))
+			}
+		}
+	}
+
+	return r.checkSelections(from, to)
+}
+
+// checkMethod checks that the renamed method does not collide with a
+// field or another method of its receiver type, and that no selector
+// expression referring to it would resolve to something else.
+func (r *Renamer) checkMethod(from *types.Func, to string) *Conflict {
+	recv := from.Type().(*types.Signature).Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+
+	if obj, _, _ := types.LookupFieldOrMethod(recv, true, from.Pkg(), to); obj != nil && !r.same(obj, from) && r.nameOf(obj) == to {
+		return r.conflict(from, to, "conflicts with %s declared at %s",
+			describe(obj), r.position(obj.Pos()))
+	}
+	if named, ok := recv.(*types.Named); ok {
+		if m := r.methodNamed(named, to, from); m != nil {
+			return r.conflict(from, to, "conflicts with %s declared at %s",
+				describe(m), r.position(m.Pos()))
+		}
+		if st, ok := named.Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				if f := st.Field(i); r.nameOf(f) == to {
+					return r.conflict(from, to, "conflicts with %s declared at %s",
+						describe(f), r.position(f.Pos()))
+				}
+			}
+		}
+	}
+	if iface, ok := recv.Underlying().(*types.Interface); ok {
+		for i := 0; i < iface.NumMethods(); i++ {
+			if m := iface.Method(i); !r.same(m, from) && r.nameOf(m) == to {
+				return r.conflict(from, to, "conflicts with %s declared at %s",
+					describe(m), r.position(m.Pos()))
+			}
+		}
+	}
+
+	return r.checkSelections(from, to)
+}
+
+// checkSelections checks every selector expression x.from in the
+// program: after the rename, x.to must still denote from, which fails
+// if the type of x has another field or method of that name at the same
+// or a shallower embedding depth. Conversely, a selector expression x.to
+// that denotes a field or method promoted from deeper than from must not
+// be captured by it.
+func (r *Renamer) checkSelections(from types.Object, to string) *Conflict {
+	for _, pkg := range r.pkgs {
+		// Those of from are among them, as from is to be named to.
+		for _, id := range r.selectionsNamed(pkg, to) {
+			sel := pkg.TypesInfo.Selections[id]
+			if !r.same(sel.Obj(), from) {
+				obj, index, _ := types.LookupFieldOrMethod(sel.Recv(), true, from.Pkg(), from.Name())
+				if obj == nil || !r.same(obj, from) || len(index) > len(sel.Index()) {
+					continue
+				}
+				return r.conflict(from, to, "selection at %s of %s declared at %s would refer to it",
+					r.position(id.Sel.Pos()), describe(sel.Obj()), r.position(sel.Obj().Pos()))
+			}
+			obj, _, _ := types.LookupFieldOrMethod(sel.Recv(), true, from.Pkg(), to)
+			if obj == nil || r.same(obj, from) || r.nameOf(obj) != to {
+				continue
+			}
+			return r.conflict(from, to, "selection at %s would refer to %s declared at %s",
+				r.position(id.Sel.Pos()), describe(obj), r.position(obj.Pos()))
+		}
+	}
+	return nil
+}
+
+func (r *Renamer) methodNamed(named *types.Named, name string, self types.Object) *types.Func {
+	for i := 0; i < named.NumMethods(); i++ {
+		if m := named.Method(i); !r.same(m, self) && r.nameOf(m) == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// lookup returns an object other than self in scope s that is or will
+// be named name. If pos is valid, only objects declared before pos are
+// considered for local scopes.
+func (r *Renamer) lookup(s *types.Scope, name string, self types.Object, pos token.Pos) types.Object {
+	if s == nil {
+		return nil
+	}
+	for _, obj := range r.scopeNamed(s, name) {
+		if r.same(obj, self) {
+			continue
+		}
+		if pos.IsValid() && obj.Pkg() != nil && obj.Parent() != obj.Pkg().Scope() && obj.Pos() > pos {
+			continue
+		}
+		return obj
+	}
+	return nil
+}
+
+// nameOf returns the name obj will have once all pending renames are
+// applied.
+func (r *Renamer) nameOf(obj types.Object) string {
+	if spec, ok := r.objsToUpdate[r.key(obj)]; ok {
+		return spec.To
+	}
+	return obj.Name()
+}
+
+func (r *Renamer) position(pos token.Pos) token.Position {
+	return r.fset.Position(pos)
+}
+
+func (r *Renamer) conflict(from types.Object, to string, format string, args ...interface{}) *Conflict {
+	msg := fmt.Sprintf("renaming this %s to %q ", describe(from), to)
+	return &Conflict{
+		Obj: from,
+		To:  to,
+		Pos: r.position(from.Pos()),
+		Msg: msg + fmt.Sprintf(format, args...),
+	}
+}
+
+// describe returns a short description of obj such as `var "x"`.
+func describe(obj types.Object) string {
+	var kind string
+	switch obj := obj.(type) {
+	case *types.Var:
+		if obj.IsField() {
+			kind = "field"
+		} else {
+			kind = "var"
+		}
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			kind = "method"
+		} else {
+			kind = "func"
+		}
+	case *types.Const:
+		kind = "const"
+	case *types.TypeName:
+		if _, ok := obj.Type().(*types.TypeParam); ok {
+			kind = "type parameter"
+		} else {
+			kind = "type"
+		}
+	case *types.PkgName:
+		kind = "package name"
+	case *types.Label:
+		kind = "label"
+	case *types.Builtin:
+		kind = "builtin"
+	case *types.Nil:
+		kind = "nil"
+	default:
+		kind = "object"
+	}
+	return fmt.Sprintf("%s %q", kind, obj.Name())
+}
+
+func encloses(outer, inner *types.Scope) bool {
+	for s := inner; s != nil; s = s.Parent() {
+		if s == outer {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedIdents(m map[*ast.Ident]types.Object) []*ast.Ident {
+	ids := make([]*ast.Ident, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sortIdents(ids)
+	return ids
+}
+
+func sortIdents(ids []*ast.Ident) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Pos() < ids[j].Pos()
+	})
+}
+
+func sortedSelections(m map[*ast.SelectorExpr]*types.Selection) []*ast.SelectorExpr {
+	sels := make([]*ast.SelectorExpr, 0, len(m))
+	for sel := range m {
+		sels = append(sels, sel)
+	}
+	sortSelectors(sels)
+	return sels
+}
+
+func sortSelectors(sels []*ast.SelectorExpr) {
+	sort.Slice(sels, func(i, j int) bool {
+		return sels[i].Pos() < sels[j].Pos()
+	})
+}
@@ -0,0 +1,343 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// loadTestPackage type-checks src as the single file of package p.
+func loadTestPackage(t *testing.T, src string) (*token.FileSet, *packages.Package) {
+	fset, pkgs := loadTestPackages(t, [][2]string{{"p", src}})
+	return fset, pkgs[0]
+}
+
+// pkgImporter imports the packages type-checked so far, and the fake
+// ones otherwise.
+type pkgImporter map[string]*types.Package
+
+func (m pkgImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := m[path]; ok {
+		return pkg, nil
+	}
+	return fakeImporter{}.Import(path)
+}
+
+// loadTestPackages type-checks the packages given as path and source
+// pairs in order, so that each may import the preceding ones.
+func loadTestPackages(t *testing.T, srcs [][2]string) (*token.FileSet, []*packages.Package) {
+	fset := token.NewFileSet()
+	imp := make(pkgImporter)
+	var pkgs []*packages.Package
+	for _, src := range srcs {
+		filename := "/src/" + src[0] + "/" + path.Base(src[0]) + ".go"
+		f, err := parser.ParseFile(fset, filename, src[1], parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+			Instances:  make(map[*ast.Ident]types.Instance),
+		}
+		conf := types.Config{Importer: imp}
+		tpkg, err := conf.Check(src[0], fset, []*ast.File{f}, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imp[src[0]] = tpkg
+		pkgs = append(pkgs, &packages.Package{
+			ID:              src[0],
+			Name:            tpkg.Name(),
+			PkgPath:         src[0],
+			GoFiles:         []string{filename},
+			CompiledGoFiles: []string{filename},
+			Fset:            fset,
+			Syntax:          []*ast.File{f},
+			Types:           tpkg,
+			TypesInfo:       info,
+		})
+	}
+	return fset, pkgs
+}
+
+// fakePackages are the sources of the packages fakeImporter provides.
+var fakePackages = map[string]string{
+	"encoding/json": `package json
+func Marshal(v any) ([]byte, error) { return nil, nil }`,
+	"example.com/dep": `package dep
+type Getter interface{ Get_Value() int }`,
+	"reflect": `package reflect
+type Value struct{}
+func ValueOf(i any) Value { return Value{} }
+func (v Value) FieldByName(name string) Value { return v }
+func (v Value) MethodByName(name string) Value { return v }`,
+}
+
+// fakeImporter imports stubs of a few standard packages.
+type fakeImporter struct{}
+
+func (fakeImporter) Import(path string) (*types.Package, error) {
+	src, ok := fakePackages[path]
+	if !ok {
+		return nil, fmt.Errorf("cannot import %q", path)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path+".go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var conf types.Config
+	return conf.Check(path, fset, []*ast.File{f}, nil)
+}
+
+// renameChecked renames the declarations in pkg whose names CheckThing
+// reports, returning how many.
+func renameChecked(r *Renamer, pkg *packages.Package) int {
+	var n int
+	defs := NewDefIndex(pkg.TypesInfo)
+	for _, f := range pkg.Syntax {
+		lint.WalkNames(pkg.Fset, f, pkg.TypesInfo, func(id *ast.Ident, thing interface{}) {
+			spec := lint.CheckThing(pkg.PkgPath, f, pkg.TypesInfo, id, thing)
+			if obj := defs.DefOf(id); spec != nil && obj != nil {
+				r.Rename(obj, *spec)
+				n++
+			}
+		})
+	}
+	return n
+}
+
+// update runs r.Update quietly, returning the contents it would write
+// by filename.
+func update(r *Renamer) (map[string]string, error) {
+	contents := make(map[string]string)
+	r.SetWriteFunc(func(filename string, content []byte) error {
+		contents[filename] = string(content)
+		return nil
+	})
+	r.SetQuiet(true)
+	err := r.Update()
+	return contents, err
+}
+
+// lookupDef returns the object defined by the first identifier named
+// name in the package.
+func lookupDef(pkg *packages.Package, name string) types.Object {
+	for _, id := range sortedIdents(pkg.TypesInfo.Defs) {
+		if obj := pkg.TypesInfo.Defs[id]; obj != nil && id.Name == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+func TestCheckConflicts(t *testing.T) {
+	testData := []struct {
+		name     string
+		src      string
+		from     string
+		to       string
+		others   map[string]string
+		conflict string
+	}{
+		{
+			name: "same block",
+			src: `package p
+func f() int {
+	user_id, userID := 1, 2
+	return user_id + userID
+}`,
+			from:     "user_id",
+			to:       "userID",
+			conflict: `conflicts with var "userID"`,
+		},
+		{
+			name: "shadow outer reference",
+			src: `package p
+var userID = 1
+func f() int {
+	user_id := 2
+	return user_id + userID
+}`,
+			from:     "user_id",
+			to:       "userID",
+			conflict: `would shadow the reference`,
+		},
+		{
+			name: "captured by nested declaration",
+			src: `package p
+var user_id = 1
+func f() int {
+	userID := 2
+	return user_id + userID
+}`,
+			from:     "user_id",
+			to:       "userID",
+			conflict: `would be shadowed by var "userID"`,
+		},
+		{
+			name: "struct field",
+			src: `package p
+type T struct {
+	User_ID int
+	UserID  int
+}`,
+			from:     "User_ID",
+			to:       "UserID",
+			conflict: `conflicts with field "UserID"`,
+		},
+		{
+			name: "method vs field",
+			src: `package p
+type T struct {
+	UserID int
+}
+func (T) User_ID() int { return 0 }`,
+			from:     "User_ID",
+			to:       "UserID",
+			conflict: `conflicts with field "UserID"`,
+		},
+		{
+			name: "promoted field",
+			src: `package p
+type Inner struct {
+	UserID int
+}
+type Outer struct {
+	Inner
+	User_ID int
+}
+func f(o Outer) int { return o.User_ID + o.UserID }`,
+			from:     "User_ID",
+			to:       "UserID",
+			conflict: `would refer to field "UserID"`,
+		},
+		{
+			name: "no conflict in disjoint scopes",
+			src: `package p
+func f() int {
+	user_id := 1
+	return user_id
+}
+func g() int {
+	userID := 2
+	return userID
+}`,
+			from: "user_id",
+			to:   "userID",
+		},
+		{
+			name: "name freed by another rename",
+			src: `package p
+var userID, user_id = 1, 2`,
+			from:   "user_id",
+			to:     "userID",
+			others: map[string]string{"userID": "uid"},
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkg := loadTestPackage(t, tt.src)
+		r := New(fset, []*packages.Package{pkg})
+		renames := map[string]string{tt.from: tt.to}
+		for from, to := range tt.others {
+			renames[from] = to
+		}
+		for from, to := range renames {
+			obj := lookupDef(pkg, from)
+			if obj == nil {
+				t.Fatalf("Test: %s, %s not found", tt.name, from)
+			}
+			r.Rename(obj, lint.Spec{Id: &ast.Ident{Name: from}, To: to})
+		}
+
+		conflicts := r.checkConflicts()
+		switch {
+		case tt.conflict == "" && len(conflicts) != 0:
+			t.Errorf("Test: %s, unexpected conflict: %v", tt.name, conflicts[0])
+		case tt.conflict != "" && len(conflicts) == 0:
+			t.Errorf("Test: %s, expected a conflict", tt.name)
+		case tt.conflict != "" && !strings.Contains(conflicts[0].Error(), tt.conflict):
+			t.Errorf("Test: %s, expected: %q, got: %q", tt.name, tt.conflict, conflicts[0].Error())
+		}
+	}
+}
+
+func TestDotImportConflicts(t *testing.T) {
+	p := `package p
+
+func Get_User_ID() int { return 1 }
+`
+	testData := []struct {
+		name     string
+		q        string
+		conflict string
+	}{
+		{
+			name: "package-level declaration",
+			q: `package q
+
+import . "example.com/p"
+
+func GetUserID() int { return Get_User_ID() }
+`,
+			conflict: `dot-imported at /src/example.com/q/q.go:3:8 conflicts with func "GetUserID" declared at /src/example.com/q/q.go:5:6`,
+		},
+		{
+			name: "local declaration",
+			q: `package q
+
+import . "example.com/p"
+
+func f() int {
+	GetUserID := 2
+	return GetUserID + Get_User_ID()
+}
+`,
+			conflict: `reference at /src/example.com/q/q.go:7:21 would be shadowed by var "GetUserID" declared at /src/example.com/q/q.go:6:2`,
+		},
+		{
+			name: "no conflict",
+			q: `package q
+
+import . "example.com/p"
+
+func f() int {
+	userID := 2
+	return userID + Get_User_ID()
+}
+`,
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkgs := loadTestPackages(t, [][2]string{{"example.com/p", p}, {"example.com/q", tt.q}})
+		r := New(fset, pkgs)
+		r.Rename(lookupDef(pkgs[0], "Get_User_ID"), lint.Spec{Id: &ast.Ident{Name: "Get_User_ID"}, To: "GetUserID"})
+
+		conflicts := r.checkConflicts()
+		if tt.conflict == "" {
+			if len(conflicts) != 0 {
+				t.Errorf("Test: %s, unexpected conflicts: %v", tt.name, conflicts)
+			}
+			continue
+		}
+		if len(conflicts) == 0 || !strings.Contains(conflicts[0].Error(), tt.conflict) {
+			t.Errorf("Test: %s, expected conflict %q, got: %v", tt.name, tt.conflict, conflicts)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package rename
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// SetRewriteComments makes Update rewrite the renamed names in every
+// comment of the packages that declare them, not only in their doc
+// comments and in doc links.
+func (r *Renamer) SetRewriteComments(all bool) {
+	r.rewriteComments = all
+}
+
+var docLinkRE = regexp.MustCompile(`\[([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*){0,2})\]`)
+
+// replaceWord replaces the whole-word occurrences of from in text.
+func replaceWord(text, from, to string) string {
+	if !strings.Contains(text, from) {
+		return text
+	}
+	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(from) + `\b`)
+	return re.ReplaceAllLiteralString(text, to)
+}
+
+// updateComments rewrites the old names of the renamed objects in the
+// comments of the initial packages and returns the names of the files
+// it changed. It must be called before the syntax trees are formatted.
+func (r *Renamer) updateComments() map[string]bool {
+	changed := make(map[string]bool)
+	for _, pkg := range r.pkgs {
+		// renamed names declared in the package, for -comments
+		words := make(map[string]string)
+		if r.rewriteComments {
+			for _, id := range sortedIdents(pkg.TypesInfo.Defs) {
+				obj := pkg.TypesInfo.Defs[id]
+				if obj == nil {
+					continue
+				}
+				if spec, ok := r.objsToUpdate[r.key(obj)]; ok {
+					if prev, ok := words[obj.Name()]; ok && prev != spec.To {
+						spec.To = "" // ambiguous
+					}
+					words[obj.Name()] = spec.To
+				}
+			}
+		}
+
+		for _, f := range pkg.Syntax {
+			edit := func(c *ast.Comment, text string) {
+				if text != c.Text {
+					c.Text = text
+					changed[r.position(f.Pos()).Filename] = true
+				}
+			}
+
+			// The doc comments of the renamed declarations.
+			for _, doc := range r.renamedDocs(pkg, f) {
+				for _, c := range doc.group.List {
+					edit(c, replaceWord(c.Text, doc.from, doc.to))
+				}
+			}
+
+			for _, cg := range f.Comments {
+				for _, c := range cg.List {
+					text := docLinkRE.ReplaceAllStringFunc(c.Text, func(link string) string {
+						return "[" + r.resolveDocLink(pkg, f, link[1:len(link)-1]) + "]"
+					})
+					if r.rewriteComments {
+						var names []string
+						for from, to := range words {
+							if to != "" {
+								names = append(names, from)
+							}
+						}
+						sort.Strings(names)
+						for _, from := range names {
+							text = replaceWord(text, from, words[from])
+						}
+					}
+					edit(c, text)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+type renamedDoc struct {
+	group    *ast.CommentGroup
+	from, to string
+}
+
+// renamedDocs returns the doc comments, and the trailing comments, of
+// the declarations in f of the objects to be renamed.
+func (r *Renamer) renamedDocs(pkg *packages.Package, f *ast.File) []renamedDoc {
+	var docs []renamedDoc
+	add := func(id *ast.Ident, groups ...*ast.CommentGroup) {
+		obj := pkg.TypesInfo.Defs[id]
+		if obj == nil {
+			return
+		}
+		spec, ok := r.objsToUpdate[r.key(obj)]
+		if !ok {
+			return
+		}
+		for _, cg := range groups {
+			if cg != nil {
+				docs = append(docs, renamedDoc{cg, obj.Name(), spec.To})
+			}
+		}
+	}
+	ast.Inspect(f, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncDecl:
+			add(n.Name, n.Doc)
+		case *ast.GenDecl:
+			// The doc comment of an unparenthesized declaration is
+			// attached to the GenDecl.
+			var doc *ast.CommentGroup
+			if !n.Lparen.IsValid() {
+				doc = n.Doc
+			}
+			for _, spec := range n.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Name, doc, s.Doc, s.Comment)
+				case *ast.ValueSpec:
+					for _, id := range s.Names {
+						add(id, doc, s.Doc, s.Comment)
+					}
+				}
+			}
+		case *ast.Field:
+			for _, id := range n.Names {
+				add(id, n.Doc, n.Comment)
+			}
+		}
+		return true
+	})
+	return docs
+}
+
+// resolveDocLink returns the doc link text, such as "Name", "T.Method"
+// or "pkg.Name", with the names it refers to renamed.
+func (r *Renamer) resolveDocLink(pkg *packages.Package, f *ast.File, text string) string {
+	parts := strings.Split(text, ".")
+	scope := pkg.Types.Scope()
+
+	// A qualified link starts with the name of an imported package.
+	if len(parts) > 1 && scope.Lookup(parts[0]) == nil {
+		for _, imp := range f.Imports {
+			obj := pkg.TypesInfo.Implicits[imp]
+			if imp.Name != nil {
+				obj = pkg.TypesInfo.Defs[imp.Name]
+			}
+			if pn, ok := obj.(*types.PkgName); ok && pn.Name() == parts[0] {
+				return parts[0] + "." + r.resolveMembers(pn.Imported().Scope(), parts[1:])
+			}
+		}
+		return text
+	}
+	return r.resolveMembers(scope, parts)
+}
+
+// resolveMembers renames the parts of a doc link that name an object in
+// scope and, optionally, a field or method of it.
+func (r *Renamer) resolveMembers(scope *types.Scope, parts []string) string {
+	obj := scope.Lookup(parts[0])
+	if obj == nil || len(parts) > 2 {
+		return strings.Join(parts, ".")
+	}
+	result := []string{r.nameOf(obj)}
+	if len(parts) == 2 {
+		member := parts[1]
+		if tn, ok := obj.(*types.TypeName); ok {
+			if m, _, _ := types.LookupFieldOrMethod(tn.Type(), true, tn.Pkg(), member); m != nil {
+				member = r.nameOf(m)
+			}
+		}
+		result = append(result, member)
+	}
+	return strings.Join(result, ".")
+}
@@ -0,0 +1,110 @@
+package rename
+
+import (
+	"go/ast"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+const commentsSrc = `package p
+
+// Parse_URL parses a URL, unlike Parse_URLs.
+// See also [User_Info.Get_Name] and [Max_Len].
+func Parse_URL() {}
+
+// User_Info holds a user.
+type User_Info struct {
+	User_Id int // User_Id is unique
+}
+
+// Get_Name returns the name.
+func (u User_Info) Get_Name() string { return "" }
+
+// Max_Len is the maximum length.
+const Max_Len = 10
+
+func f() {
+	// call Parse_URL with Max_Len
+	Parse_URL()
+}
+`
+
+func TestUpdateComments(t *testing.T) {
+	testData := []struct {
+		name     string
+		all      bool
+		expected string
+	}{
+		{
+			name: "doc comments",
+			expected: `package p
+
+// ParseURL parses a URL, unlike Parse_URLs.
+// See also [UserInfo.GetName] and [MaxLen].
+func ParseURL() {}
+
+// UserInfo holds a user.
+type UserInfo struct {
+	UserID int // UserID is unique
+}
+
+// GetName returns the name.
+func (u UserInfo) GetName() string { return "" }
+
+// MaxLen is the maximum length.
+const MaxLen = 10
+
+func f() {
+	// call Parse_URL with Max_Len
+	ParseURL()
+}
+`,
+		},
+		{
+			name: "all comments",
+			all:  true,
+			expected: `package p
+
+// ParseURL parses a URL, unlike Parse_URLs.
+// See also [UserInfo.GetName] and [MaxLen].
+func ParseURL() {}
+
+// UserInfo holds a user.
+type UserInfo struct {
+	UserID int // UserID is unique
+}
+
+// GetName returns the name.
+func (u UserInfo) GetName() string { return "" }
+
+// MaxLen is the maximum length.
+const MaxLen = 10
+
+func f() {
+	// call ParseURL with MaxLen
+	ParseURL()
+}
+`,
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkg := loadTestPackage(t, commentsSrc)
+		r := New(fset, []*packages.Package{pkg})
+		r.SetRewriteComments(tt.all)
+		for _, name := range []string{"Parse_URL", "User_Info", "User_Id", "Get_Name", "Max_Len"} {
+			spec := lint.Check(&ast.Ident{Name: name})
+			r.Rename(lookupDef(pkg, name), *spec)
+		}
+		contents, err := update(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := contents["/src/p/p.go"]; actual != tt.expected {
+			t.Errorf("Test: %s, expected:\n%s\ngot:\n%s", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -1,30 +1,326 @@
 package rename
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
-var DiffCmd = "diff"
+// DiffContext is the number of unchanged lines shown around each change
+// by Diff.
+var DiffContext = 3
 
+// Diff writes a unified diff between the file on disk and content to the
+// standard output.
 func Diff(filename string, content []byte) error {
-	renamed := fmt.Sprintf("%s.%d.renamed", filename, os.Getpid())
-	if err := ioutil.WriteFile(renamed, content, 0644); err != nil {
-		return err
+	return DiffTo(os.Stdout, DiffContext)(filename, content)
+}
+
+// DiffTo returns a function suitable for SetWriteFunc that writes a
+// unified diff between each file on disk and its new content to w,
+// with the given number of context lines. A file that does not exist
+// yet is diffed against /dev/null.
+func DiffTo(w io.Writer, context int) func(filename string, content []byte) error {
+	return func(filename string, content []byte) error {
+		old, created, err := readOld(filename)
+		if err != nil {
+			return err
+		}
+		if !created && bytes.Equal(old, content) {
+			return nil
+		}
+		name := diffName(filename)
+		from := "a/" + name
+		if created {
+			from = "/dev/null"
+		}
+		return writeDiff(w, from, "b/"+name, old, content, context)
 	}
-	defer os.Remove(renamed)
+}
 
-	diff, err := exec.Command(DiffCmd, "-u", filename, renamed).CombinedOutput()
-	if len(diff) > 0 {
-		// diff exits with a non-zero status when the files don't match.
-		// Ignore that failure as long as we get output.
-		os.Stdout.Write(diff)
-		return nil
+// PatchTo returns a function suitable for SetWriteFunc that appends a
+// git-style diff for each file to w. Paths are relative to root, so the
+// accumulated output can be applied from root with "git apply" or
+// "patch -p1".
+func PatchTo(w io.Writer, root string, context int) func(filename string, content []byte) error {
+	return func(filename string, content []byte) error {
+		rel, err := filepath.Rel(root, filename)
+		if err != nil || isOutside(rel) {
+			return fmt.Errorf("%s is outside of %s", filename, root)
+		}
+		old, created, err := readOld(filename)
+		if err != nil {
+			return err
+		}
+		if !created && bytes.Equal(old, content) {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		fmt.Fprintf(w, "diff --git a/%s b/%s\n", name, name)
+		from := "a/" + name
+		if created {
+			fmt.Fprintf(w, "new file mode 100644\n")
+			from = "/dev/null"
+		}
+		return writeDiff(w, from, "b/"+name, old, content, context)
+	}
+}
+
+// readOld reads the current content of filename, reporting whether the
+// file is yet to be created.
+func readOld(filename string) (old []byte, created bool, err error) {
+	old, err = ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, true, nil
 	}
 	if err != nil {
-		return fmt.Errorf("computing diff: %v", err)
+		return nil, false, fmt.Errorf("computing diff: %v", err)
+	}
+	return old, false, nil
+}
+
+// diffName returns filename relative to the current directory if it
+// lies beneath it, in slash-separated form.
+func diffName(filename string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, filename); err == nil && !isOutside(rel) {
+			filename = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filename), "/")
+}
+
+// isOutside reports whether the relative path rel leads out of the
+// directory it is relative to. A name merely starting with dots, like
+// ..file, does not.
+func isOutside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// WriteDiff writes a unified diff from old to new to w, labelling the
+// two sides a/name and b/name. Nothing is written if old and new are
+// equal.
+func WriteDiff(w io.Writer, name string, old, new []byte, context int) error {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	return writeDiff(w, "a/"+name, "b/"+name, old, new, context)
+}
+
+func writeDiff(w io.Writer, from, to string, old, new []byte, context int) error {
+	if context < 0 {
+		context = 0
+	}
+
+	a, b := splitLines(old), splitLines(new)
+	edits := diffLines(a, b)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- %s\n", from)
+	fmt.Fprintf(bw, "+++ %s\n", to)
+	for _, h := range hunks(edits, context) {
+		var na, nb int
+		for _, e := range h {
+			if e.kind != '+' {
+				na++
+			}
+			if e.kind != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(bw, "@@ -%s +%s @@\n", hunkRange(h[0].a, na), hunkRange(h[0].b, nb))
+		for _, e := range h {
+			line := e.line(a, b)
+			bw.WriteByte(e.kind)
+			bw.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				bw.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// hunkRange formats the start,length part of a hunk header, following
+// the conventions of GNU diff.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, n)
+	}
+}
+
+// An edit is one line of a diff: kind is ' ' for a line common to both
+// sides, '-' for a line only in a and '+' for a line only in b. a and b
+// are the indexes of the line (or of the next line) in each side.
+type edit struct {
+	kind byte
+	a, b int
+}
+
+func (e edit) line(a, b []string) string {
+	if e.kind == '+' {
+		return b[e.b]
+	}
+	return a[e.a]
+}
+
+// splitLines splits text into lines, each keeping its newline; the last
+// line lacks one if text does not end with a newline.
+func splitLines(text []byte) []string {
+	var lines []string
+	for len(text) > 0 {
+		i := bytes.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		lines = append(lines, string(text[:i]))
+		text = text[i:]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script from a to b using Myers'
+// O(ND) algorithm, after stripping the common prefix and suffix.
+func diffLines(a, b []string) []edit {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{' ', i, i})
+	}
+	for _, e := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		e.a += prefix
+		e.b += prefix
+		edits = append(edits, e)
+	}
+	for i := suffix; i > 0; i-- {
+		edits = append(edits, edit{' ', len(a) - i, len(b) - i})
+	}
+	return edits
+}
+
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	// v[max+k] is the furthest x reached on diagonal k; trace[d] is a
+	// copy of v before step d, for backtracking.
+	v := make([]int, 2*max+2)
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[max+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', x, y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{'+', x, y})
+		} else {
+			x--
+			edits = append(edits, edit{'-', x, y})
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// hunks groups the changes in edits with up to context common lines
+// around each, merging groups whose contexts touch.
+func hunks(edits []edit, context int) [][]edit {
+	var result [][]edit
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			// Find the end of this run of changes.
+			for end < len(edits) && edits[end].kind != ' ' {
+				end++
+			}
+			// Merge with the next run if the gap is small enough.
+			next := end
+			for next < len(edits) && edits[next].kind == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		i = end
+		end += context
+		if end > len(edits) {
+			end = len(edits)
+		}
+		result = append(result, edits[start:end])
 	}
-	return nil
+	return result
 }
@@ -0,0 +1,168 @@
+package rename
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteDiff(t *testing.T) {
+	testData := []struct {
+		name     string
+		old, new string
+		context  int
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			context:  3,
+			expected: "",
+		},
+		{
+			name:    "single change",
+			old:     "a\nb\nc\nd\ne\n",
+			new:     "a\nb\nC\nd\ne\n",
+			context: 1,
+			expected: `--- a/p.go
++++ b/p.go
+@@ -2,3 +2,3 @@
+ b
+-c
++C
+ d
+`,
+		},
+		{
+			name:    "separate hunks",
+			old:     "a\nb\nc\nd\ne\nf\ng\n",
+			new:     "A\nb\nc\nd\ne\nf\nG\n",
+			context: 1,
+			expected: `--- a/p.go
++++ b/p.go
+@@ -1,2 +1,2 @@
+-a
++A
+ b
+@@ -6,2 +6,2 @@
+ f
+-g
++G
+`,
+		},
+		{
+			name:    "merged hunks",
+			old:     "a\nb\nc\nd\n",
+			new:     "A\nb\nc\nD\n",
+			context: 1,
+			expected: `--- a/p.go
++++ b/p.go
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+-d
++D
+`,
+		},
+		{
+			name:    "insertion without context",
+			old:     "a\nb\n",
+			new:     "a\nx\nb\n",
+			context: 0,
+			expected: `--- a/p.go
++++ b/p.go
+@@ -1,0 +2 @@
++x
+`,
+		},
+		{
+			name:    "no newline at end of file",
+			old:     "a\nb",
+			new:     "a\nc",
+			context: 3,
+			expected: `--- a/p.go
++++ b/p.go
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tt := range testData {
+		var buf bytes.Buffer
+		if err := WriteDiff(&buf, "p.go", []byte(tt.old), []byte(tt.new), tt.context); err != nil {
+			t.Errorf("Test: %s, unexpected error: %v", tt.name, err)
+			continue
+		}
+		if actual := buf.String(); tt.expected != actual {
+			t.Errorf("Test: %s, expected:\n%s\ngot:\n%s", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestPatchTo(t *testing.T) {
+	root, err := ioutil.TempDir("", "fixname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "p"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(root, "p", "p.go")
+	if err := ioutil.WriteFile(filename, []byte("package p\n\nvar user_id int\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	write := PatchTo(&buf, root, 0)
+	if err := write(filename, []byte("package p\n\nvar userID int\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(filepath.Join(root, "p", AliasFile), []byte("package p\n")); err != nil {
+		t.Fatal(err)
+	}
+	// A name starting with dots is still beneath root.
+	if err := write(filepath.Join(root, "..p.go"), []byte("package p\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `diff --git a/p/p.go b/p/p.go
+--- a/p/p.go
++++ b/p/p.go
+@@ -3 +3 @@
+-var user_id int
++var userID int
+diff --git a/p/fixname_aliases.go b/p/fixname_aliases.go
+new file mode 100644
+--- /dev/null
++++ b/p/fixname_aliases.go
+@@ -0,0 +1 @@
++package p
+diff --git a/..p.go b/..p.go
+new file mode 100644
+--- /dev/null
++++ b/..p.go
+@@ -0,0 +1 @@
++package p
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	for _, filename := range []string{root + "x.go", filepath.Join(filepath.Dir(root), "p.go")} {
+		if err := write(filename, nil); err == nil {
+			t.Errorf("expected an error for %s outside of %s", filename, root)
+		}
+	}
+}
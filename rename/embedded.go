@@ -0,0 +1,66 @@
+package rename
+
+import (
+	"go/types"
+)
+
+// embeddedTypeName returns the type name an embedded field of type t is
+// named after.
+func embeddedTypeName(t types.Type) *types.TypeName {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	switch t := t.(type) {
+	case *types.Alias:
+		return t.Obj()
+	case *types.Named:
+		return t.Obj()
+	}
+	return nil
+}
+
+// embeddedFields returns the embedded fields declared in the initial
+// packages, by the key of the type name they are named after.
+func (r *Renamer) embeddedFields() map[objKey][]*types.Var {
+	fields := make(map[objKey][]*types.Var)
+	seen := make(map[objKey]bool)
+	for _, pkg := range r.pkgs {
+		for _, id := range sortedIdents(pkg.TypesInfo.Defs) {
+			field, ok := pkg.TypesInfo.Defs[id].(*types.Var)
+			if !ok || !field.Embedded() || seen[r.key(field)] {
+				continue
+			}
+			seen[r.key(field)] = true
+			tn := embeddedTypeName(field.Type())
+			if tn == nil || tn.Name() != field.Name() {
+				continue
+			}
+			key := r.key(tn)
+			fields[key] = append(fields[key], field)
+		}
+	}
+	return fields
+}
+
+// embeddedGroup returns the objects that must be renamed together with
+// obj if it is a type name or an embedded field: the type name and the
+// fields embedding it, whose names are the same identifiers.
+func (r *Renamer) embeddedGroup(obj types.Object, fields map[objKey][]*types.Var) []types.Object {
+	tn, ok := obj.(*types.TypeName)
+	if v, isVar := obj.(*types.Var); isVar && v.Embedded() {
+		tn = embeddedTypeName(v.Type())
+		ok = tn != nil && tn.Name() == v.Name()
+	}
+	if !ok || len(fields[r.key(tn)]) == 0 {
+		return nil
+	}
+	group := []types.Object{tn}
+	for _, f := range fields[r.key(tn)] {
+		group = append(group, f)
+	}
+	if _, ok := obj.(*types.Var); ok && r.packageOf(obj) == nil {
+		// a field of a dependency renamed by Apply
+		group = append(group, obj)
+	}
+	return group
+}
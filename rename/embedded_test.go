@@ -0,0 +1,183 @@
+package rename
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestEmbeddedFields(t *testing.T) {
+	src := `package p
+
+type Http_Client struct{ Base_Url string }
+
+func (c *Http_Client) Do_Request() {}
+
+type Api struct {
+	*Http_Client
+	Name string
+}
+
+func f(a Api) {
+	a.Http_Client.Do_Request()
+	a.Do_Request()
+	_ = a.Base_Url
+	_ = Api{Http_Client: &Http_Client{Base_Url: ""}}
+}
+`
+	expected := `package p
+
+type HTTPClient struct{ BaseURL string }
+
+func (c *HTTPClient) DoRequest() {}
+
+type Api struct {
+	*HTTPClient
+	Name string
+}
+
+func f(a Api) {
+	a.HTTPClient.DoRequest()
+	a.DoRequest()
+	_ = a.BaseURL
+	_ = Api{HTTPClient: &HTTPClient{BaseURL: ""}}
+}
+`
+	fset, pkg := loadTestPackage(t, src)
+	r := New(fset, []*packages.Package{pkg})
+	client := lookupDef(pkg, "Http_Client")
+	renames := map[types.Object]string{
+		client: "HTTPClient",
+		lookupMethod(pkg, "Http_Client", "Base_Url"):   "BaseURL",
+		lookupMethod(pkg, "Http_Client", "Do_Request"): "DoRequest",
+	}
+	for obj, to := range renames {
+		r.Rename(obj, lint.Spec{Id: &ast.Ident{Name: obj.Name()}, To: to})
+	}
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := contents["/src/p/p.go"]; actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+	if group := r.Group(client); len(group) != 2 {
+		t.Errorf("expected the type and the embedded field in the group, got: %v", group)
+	}
+}
+
+func TestEmbeddedConflicts(t *testing.T) {
+	testData := []struct {
+		name      string
+		src       string
+		from      string
+		to        string
+		protected string // type whose embedded field is protected
+		conflict  string
+	}{
+		{
+			name: "embedded field vs field",
+			src: `package p
+type Http_Client struct{}
+type Api struct {
+	Http_Client
+	HTTPClient int
+}`,
+			from:     "Http_Client",
+			to:       "HTTPClient",
+			conflict: `conflicts with field "HTTPClient"`,
+		},
+		{
+			name: "protected embedded field",
+			src: `package p
+type http_client struct{}
+type Api struct{ *http_client }`,
+			from:      "http_client",
+			to:        "httpClient",
+			protected: "Api",
+			conflict:  `which is protected`,
+		},
+		{
+			name: "captured promoted field",
+			src: `package p
+type Inner struct {
+	UserID int
+}
+type Outer struct {
+	Inner
+	User_ID int
+}
+func f(o Outer) int { return o.UserID }`,
+			from:     "User_ID",
+			to:       "UserID",
+			conflict: `selection at /src/p/p.go:9:32 of field "UserID" declared at /src/p/p.go:3:2 would refer to it`,
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkg := loadTestPackage(t, tt.src)
+		r := New(fset, []*packages.Package{pkg})
+		if tt.protected != "" {
+			r.SetProtected(func(obj types.Object) bool {
+				return r.same(obj, lookupMethod(pkg, tt.protected, obj.Name()))
+			})
+		}
+		r.Rename(lookupDef(pkg, tt.from), lint.Spec{Id: &ast.Ident{Name: tt.from}, To: tt.to})
+
+		conflicts := r.checkConflicts()
+		if len(conflicts) == 0 || !strings.Contains(conflicts[0].Error(), tt.conflict) {
+			t.Errorf("Test: %s, expected conflict %q, got: %v", tt.name, tt.conflict, conflicts)
+		}
+		if len(r.objsToUpdate) != 0 {
+			t.Errorf("Test: %s, expected the whole group to be refused, got %d renames", tt.name, len(r.objsToUpdate))
+		}
+	}
+}
+
+func TestEmbeddedOccurrences(t *testing.T) {
+	fset, pkg := loadTestPackage(t, `package p
+type Inner struct{}
+type Outer struct {
+	Inner
+}
+func f(o Outer) Inner { return o.Inner }`)
+	r := New(fset, []*packages.Package{pkg})
+
+	typ := lookupDef(pkg, "Inner")
+	field := lookupDef(pkg, "Outer").Type().Underlying().(*types.Struct).Field(0)
+	testData := []struct {
+		obj      types.Object
+		expected []string
+	}{
+		{typ, []string{"/src/p/p.go:2:6", "/src/p/p.go:4:2", "/src/p/p.go:6:17"}},
+		{field, []string{"/src/p/p.go:4:2", "/src/p/p.go:6:34"}},
+	}
+
+	for _, tt := range testData {
+		var actual []string
+		for _, pos := range r.Occurrences(tt.obj) {
+			actual = append(actual, pos.String())
+		}
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%s: expected: %v, got: %v", tt.obj, tt.expected, actual)
+		}
+	}
+
+	// Renamed together, the type and the field share their sites.
+	r.Rename(typ, lint.Spec{Id: &ast.Ident{Name: "Inner"}, To: "Core"})
+	r.Check()
+	var actual []string
+	for _, pos := range r.GroupOccurrences(typ) {
+		actual = append(actual, pos.String())
+	}
+	expected := []string{"/src/p/p.go:2:6", "/src/p/p.go:4:2", "/src/p/p.go:6:17", "/src/p/p.go:6:34"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("group: expected: %v, got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,114 @@
+package rename
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestGenerics(t *testing.T) {
+	src := `package p
+
+type Pair[Key_Type comparable, V any] struct {
+	First_Key Key_Type
+	Val       V
+}
+
+func (p *Pair[K, V]) Get_Key() K { return p.First_Key }
+
+func Map_Keys[Key_Type comparable, V any](m map[Key_Type]V) []Key_Type {
+	var keys []Key_Type
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func f() {
+	p := Pair[string, int]{First_Key: "a"}
+	_ = p.First_Key
+	_ = p.Get_Key()
+	_ = Map_Keys[string, int](nil)
+	_ = Map_Keys(map[int]bool{})
+}
+`
+	expected := `package p
+
+type Pair[KeyType comparable, V any] struct {
+	FirstKey KeyType
+	Val      V
+}
+
+func (p *Pair[K, V]) GetKey() K { return p.FirstKey }
+
+func MapKeys[KeyType comparable, V any](m map[KeyType]V) []KeyType {
+	var keys []KeyType
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func f() {
+	p := Pair[string, int]{FirstKey: "a"}
+	_ = p.FirstKey
+	_ = p.GetKey()
+	_ = MapKeys[string, int](nil)
+	_ = MapKeys(map[int]bool{})
+}
+`
+	fset, pkg := loadTestPackage(t, src)
+	r := New(fset, []*packages.Package{pkg})
+	if n := renameChecked(r, pkg); n != 5 {
+		t.Errorf("expected 5 names to rename, got %d", n)
+	}
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := contents["/src/p/p.go"]; actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestTypeParamConflicts(t *testing.T) {
+	testData := []struct {
+		name     string
+		src      string
+		conflict string
+	}{
+		{
+			name: "parameter",
+			src: `package p
+func f[Key_Type any](KeyType int) {}`,
+			conflict: `conflicts with var "KeyType"`,
+		},
+		{
+			name: "shadowed type",
+			src: `package p
+type KeyType int
+func f[Key_Type any](k KeyType) {}`,
+			conflict: `would shadow the reference at /src/p/p.go:3:24 to type "KeyType"`,
+		},
+		{
+			name: "type parameters of a type",
+			src: `package p
+type Pair[Key_Type, KeyType any] struct{}`,
+			conflict: `conflicts with type parameter "KeyType"`,
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkg := loadTestPackage(t, tt.src)
+		r := New(fset, []*packages.Package{pkg})
+		r.Rename(lookupDef(pkg, "Key_Type"), lint.Spec{Id: &ast.Ident{Name: "Key_Type"}, To: "KeyType"})
+		conflicts := r.checkConflicts()
+		if len(conflicts) == 0 || !strings.Contains(conflicts[0].Error(), tt.conflict) {
+			t.Errorf("Test: %s, expected conflict %q, got: %v", tt.name, tt.conflict, conflicts)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rename
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// A DefIndex finds the objects declared by the identifiers of a
+// package, like info.Defs, except that for the symbolic variable of a
+// type switch, such as v in switch v := x.(type), which declares a
+// distinct implicit object in each case clause, it finds one of them.
+// They share the position of the identifier, so renaming one renames
+// them all.
+type DefIndex struct {
+	info     *types.Info
+	symbolic map[token.Pos]types.Object
+}
+
+// NewDefIndex returns a DefIndex of the package type-checked into info,
+// indexing the implicit objects of its case clauses by position.
+func NewDefIndex(info *types.Info) *DefIndex {
+	x := &DefIndex{info: info, symbolic: make(map[token.Pos]types.Object)}
+	for node, obj := range info.Implicits {
+		if _, ok := node.(*ast.CaseClause); ok {
+			x.symbolic[obj.Pos()] = obj
+		}
+	}
+	return x
+}
+
+// DefOf returns the object declared by id, or nil if there is none.
+func (x *DefIndex) DefOf(id *ast.Ident) types.Object {
+	if obj := x.info.Defs[id]; obj != nil {
+		return obj
+	}
+	if obj := x.symbolic[id.Pos()]; obj != nil && obj.Name() == id.Name {
+		return obj
+	}
+	return nil
+}
+
+// symbolicIdents returns the symbolic variables of the type switches in
+// pkg, which info.Defs maps to nil, each with the implicit object of one
+// of its case clauses.
+func symbolicIdents(pkg *packages.Package) map[*ast.Ident]types.Object {
+	m := make(map[*ast.Ident]types.Object)
+	for _, f := range pkg.Syntax {
+		ast.Inspect(f, func(node ast.Node) bool {
+			ts, ok := node.(*ast.TypeSwitchStmt)
+			if !ok {
+				return true
+			}
+			assign, ok := ts.Assign.(*ast.AssignStmt)
+			if !ok || len(assign.Lhs) != 1 {
+				return true
+			}
+			id, ok := assign.Lhs[0].(*ast.Ident)
+			if !ok {
+				return true
+			}
+			for _, clause := range ts.Body.List {
+				if obj := pkg.TypesInfo.Implicits[clause]; obj != nil {
+					m[id] = obj
+					break
+				}
+			}
+			return true
+		})
+	}
+	return m
+}
+
+// implicitObjects returns the implicit objects of the case clauses of
+// the type switches in pkg and the package names declared by its
+// imports without an alias, in source order.
+func implicitObjects(pkg *packages.Package) []types.Object {
+	var objs []types.Object
+	for _, f := range pkg.Syntax {
+		ast.Inspect(f, func(node ast.Node) bool {
+			switch node.(type) {
+			case *ast.CaseClause, *ast.ImportSpec:
+				if obj := pkg.TypesInfo.Implicits[node]; obj != nil {
+					objs = append(objs, obj)
+				}
+			}
+			return true
+		})
+	}
+	return objs
+}
@@ -0,0 +1,156 @@
+package rename
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// A useIndex holds the uses and selections of an initial package, by
+// the key of the object they refer to, in source order.
+type useIndex struct {
+	uses       map[objKey][]*ast.Ident
+	selections map[objKey][]*ast.SelectorExpr
+}
+
+// usesIn returns the index of the uses and selections of pkg, built on
+// the first call for each package.
+func (r *Renamer) usesIn(pkg *packages.Package) *useIndex {
+	if r.uses == nil {
+		r.uses = make(map[*packages.Package]*useIndex)
+	}
+	if x := r.uses[pkg]; x != nil {
+		return x
+	}
+	x := &useIndex{
+		uses:       make(map[objKey][]*ast.Ident),
+		selections: make(map[objKey][]*ast.SelectorExpr),
+	}
+	for _, id := range sortedIdents(pkg.TypesInfo.Uses) {
+		k := r.key(pkg.TypesInfo.Uses[id])
+		x.uses[k] = append(x.uses[k], id)
+	}
+	for _, sel := range sortedSelections(pkg.TypesInfo.Selections) {
+		k := r.key(pkg.TypesInfo.Selections[sel].Obj())
+		x.selections[k] = append(x.selections[k], sel)
+	}
+	r.uses[pkg] = x
+	return x
+}
+
+// A nameIndex holds the objects of scopes and the uses and selections
+// of the initial packages by the name their objects will have once the
+// pending renames are applied. It is only valid as long as the pending
+// renames do not change, so checkConflicts drops it after each round.
+type nameIndex struct {
+	scopes     map[*types.Scope]map[string][]types.Object
+	uses       map[*packages.Package]map[string][]*ast.Ident
+	selections map[*packages.Package]map[string][]*ast.SelectorExpr
+}
+
+func (r *Renamer) nameIndex() *nameIndex {
+	if r.names == nil {
+		r.names = &nameIndex{
+			scopes:     make(map[*types.Scope]map[string][]types.Object),
+			uses:       make(map[*packages.Package]map[string][]*ast.Ident),
+			selections: make(map[*packages.Package]map[string][]*ast.SelectorExpr),
+		}
+	}
+	return r.names
+}
+
+// scopeNamed returns the objects of s that are or will be named name,
+// in the order of their current names.
+func (r *Renamer) scopeNamed(s *types.Scope, name string) []types.Object {
+	x := r.nameIndex()
+	m := x.scopes[s]
+	if m == nil {
+		m = make(map[string][]types.Object)
+		for _, n := range s.Names() {
+			obj := s.Lookup(n)
+			m[r.nameOf(obj)] = append(m[r.nameOf(obj)], obj)
+		}
+		x.scopes[s] = m
+	}
+	return m[name]
+}
+
+// usesNamed returns the uses in pkg of objects that are or will be
+// named name, in source order.
+func (r *Renamer) usesNamed(pkg *packages.Package, name string) []*ast.Ident {
+	x := r.nameIndex()
+	m := x.uses[pkg]
+	if m == nil {
+		m = make(map[string][]*ast.Ident)
+		for _, ids := range r.usesIn(pkg).uses {
+			n := r.nameOf(pkg.TypesInfo.Uses[ids[0]])
+			m[n] = append(m[n], ids...)
+		}
+		for _, ids := range m {
+			sortIdents(ids)
+		}
+		x.uses[pkg] = m
+	}
+	return m[name]
+}
+
+// selectionsNamed returns the selections in pkg of fields and methods
+// that are or will be named name, in source order.
+func (r *Renamer) selectionsNamed(pkg *packages.Package, name string) []*ast.SelectorExpr {
+	x := r.nameIndex()
+	m := x.selections[pkg]
+	if m == nil {
+		m = make(map[string][]*ast.SelectorExpr)
+		for _, sels := range r.usesIn(pkg).selections {
+			n := r.nameOf(pkg.TypesInfo.Selections[sels[0]].Obj())
+			m[n] = append(m[n], sels...)
+		}
+		for _, sels := range m {
+			sortSelectors(sels)
+		}
+		x.selections[pkg] = m
+	}
+	return m[name]
+}
+
+// structOf returns the struct type that declares field, from an index of
+// the struct types of the initial packages built on the first call.
+func (r *Renamer) structOf(field *types.Var) *types.Struct {
+	if r.structs == nil {
+		r.structs = make(map[*types.Var]*types.Struct)
+		r.namedStructs = make(map[*types.Struct][]*types.Named)
+		for _, p := range r.pkgs {
+			for _, f := range p.Syntax {
+				ast.Inspect(f, func(node ast.Node) bool {
+					if s, ok := node.(*ast.StructType); ok {
+						if t, ok := p.TypesInfo.Types[s].Type.(*types.Struct); ok {
+							for i := 0; i < t.NumFields(); i++ {
+								r.structs[t.Field(i)] = t
+							}
+						}
+					}
+					return true
+				})
+			}
+			for _, id := range sortedIdents(p.TypesInfo.Defs) {
+				tn, ok := p.TypesInfo.Defs[id].(*types.TypeName)
+				if !ok || tn.IsAlias() {
+					continue
+				}
+				if named, ok := tn.Type().(*types.Named); ok {
+					if st, ok := named.Underlying().(*types.Struct); ok {
+						r.namedStructs[st] = append(r.namedStructs[st], named)
+					}
+				}
+			}
+		}
+	}
+	return r.structs[field]
+}
+
+// namedStructsOf returns the defined types whose underlying type is the
+// struct st, which structOf returned.
+func (r *Renamer) namedStructsOf(st *types.Struct) []*types.Named {
+	return r.namedStructs[st]
+}
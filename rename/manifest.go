@@ -0,0 +1,173 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// A ManifestEntry records the rename of an exported identifier, or of a
+// package, so that it can be applied to importers loaded separately.
+// Recv is the type that declares a field or method. The kind of a
+// package rename is "package".
+type ManifestEntry struct {
+	Package string `json:"package"`
+	Recv    string `json:"recv,omitempty"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Kind    string `json:"kind"`
+}
+
+// kindOf returns the kind of obj as recorded in a manifest.
+func kindOf(obj types.Object) string {
+	switch obj := obj.(type) {
+	case *types.Const:
+		return "const"
+	case *types.TypeName:
+		return "type"
+	case *types.Var:
+		if obj.IsField() {
+			return "field"
+		}
+		return "var"
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return "method"
+		}
+		return "func"
+	}
+	return ""
+}
+
+// Manifest returns the pending renames of exported API declared in the
+// initial packages, sorted by package and old name. Called after Update
+// or Check, it omits the renames refused due to conflicts.
+func (r *Renamer) Manifest() []ManifestEntry {
+	var entries []ManifestEntry
+	for key, spec := range r.objsToUpdate {
+		objs := r.objectsOf(key)
+		if len(objs) == 0 || r.packageOf(objs[0]) == nil || !r.IsAPI(objs[0]) {
+			continue
+		}
+		obj := objs[0]
+		entries = append(entries, ManifestEntry{
+			Package: obj.Pkg().Path(),
+			Recv:    owner(obj),
+			Old:     obj.Name(),
+			New:     spec.To,
+			Kind:    kindOf(obj),
+		})
+	}
+	for path, spec := range r.packages {
+		for _, pkg := range r.pkgs {
+			if pkg.PkgPath == path && r.IsAPIPackage(pkg.Types) {
+				entries = append(entries, ManifestEntry{
+					Package: path,
+					Old:     pkg.Name,
+					New:     spec.To,
+					Kind:    "package",
+				})
+				break
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Recv != b.Recv {
+			return a.Recv < b.Recv
+		}
+		return a.Old < b.Old
+	})
+	return entries
+}
+
+// WriteManifest writes entries to w as an indented JSON array.
+func WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	if entries == nil {
+		entries = []ManifestEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadManifest reads a manifest written by WriteManifest.
+func ReadManifest(filename string) ([]ManifestEntry, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	for i, e := range entries {
+		if e.Package == "" || e.Old == "" || e.New == "" || e.Kind == "" {
+			return nil, fmt.Errorf("%s: entry %d: package, old, new and kind are required", filename, i)
+		}
+	}
+	return entries, nil
+}
+
+// Apply schedules the renames in entries for every object they match
+// that is referred to from the initial packages, typically an object
+// of a dependency, and returns the number of objects matched. A package
+// rename matches the package names declared by its imports.
+func (r *Renamer) Apply(entries []ManifestEntry) int {
+	type entryKey struct {
+		pkg, recv, name, kind string
+	}
+	m := make(map[entryKey]string)
+	for _, e := range entries {
+		m[entryKey{e.Package, e.Recv, e.Old, e.Kind}] = e.New
+	}
+
+	matched := make(map[objKey]bool)
+	for _, e := range entries {
+		if e.Kind != "package" {
+			continue
+		}
+		var n int
+		for _, pn := range r.importNames(e.Package) {
+			if pn.Imported().Name() == e.Old {
+				matched[r.key(pn)] = true
+				n++
+			}
+		}
+		if n > 0 {
+			r.packages[e.Package] = lint.Spec{Id: ast.NewIdent(e.Old), To: e.New}
+		}
+	}
+	for _, pkg := range r.pkgs {
+		for _, info := range []map[*ast.Ident]types.Object{pkg.TypesInfo.Defs, pkg.TypesInfo.Uses} {
+			for _, id := range sortedIdents(info) {
+				obj := info[id]
+				if obj == nil || obj.Pkg() == nil || !obj.Exported() || matched[r.key(obj)] {
+					continue
+				}
+				if kind := kindOf(obj); kind != "field" && kind != "method" && obj.Parent() != obj.Pkg().Scope() {
+					continue
+				}
+				to, ok := m[entryKey{obj.Pkg().Path(), owner(obj), obj.Name(), kindOf(obj)}]
+				if !ok {
+					continue
+				}
+				matched[r.key(obj)] = true
+				r.Rename(obj, lint.Spec{Id: id, To: to})
+			}
+		}
+	}
+	return len(matched)
+}
@@ -0,0 +1,96 @@
+package rename
+
+import (
+	"bytes"
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+const manifestSrc = `package p
+const Max_Len = 1
+type User_Info struct {
+	User_Id int
+}
+func (u *User_Info) Get_Name() string { return "" }
+func f() {
+	Max_Len := 2
+	_ = Max_Len
+}
+`
+
+func TestManifest(t *testing.T) {
+	fset, pkg := loadTestPackage(t, manifestSrc)
+	r := New(fset, []*packages.Package{pkg})
+	for _, name := range []string{"Max_Len", "User_Id", "Get_Name"} {
+		spec := lint.Check(&ast.Ident{Name: name})
+		r.Rename(lookupDef(pkg, name), *spec)
+	}
+	expected := []ManifestEntry{
+		{Package: "p", Old: "Max_Len", New: "MaxLen", Kind: "const"},
+		{Package: "p", Recv: "User_Info", Old: "Get_Name", New: "GetName", Kind: "method"},
+		{Package: "p", Recv: "User_Info", Old: "User_Id", New: "UserID", Kind: "field"},
+	}
+	entries := r.Manifest()
+	if !reflect.DeepEqual(expected, entries) {
+		t.Fatalf("expected: %v, got: %v", expected, entries)
+	}
+
+	dir, err := ioutil.TempDir("", "fixname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "manifest.json")
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, read) {
+		t.Errorf("expected: %v, got: %v", entries, read)
+	}
+}
+
+func TestApply(t *testing.T) {
+	fset, pkg := loadTestPackage(t, manifestSrc)
+	r := New(fset, []*packages.Package{pkg})
+	n := r.Apply([]ManifestEntry{
+		{Package: "p", Old: "Max_Len", New: "MaxLen", Kind: "const"},
+		{Package: "p", Recv: "User_Info", Old: "User_Id", New: "UserID", Kind: "field"},
+		{Package: "p", Recv: "Other", Old: "Get_Name", New: "GetName", Kind: "method"},
+	})
+	if n != 2 {
+		t.Errorf("expected 2 matches, got %d", n)
+	}
+
+	// The local Max_Len is not the package-level constant.
+	var consts int
+	for key := range r.objsToUpdate {
+		if key.name == "Max_Len" {
+			consts++
+		}
+	}
+	if consts != 1 {
+		t.Errorf("expected one Max_Len to be renamed, got %d", consts)
+	}
+	if to := r.nameOf(lookupDef(pkg, "User_Id")); to != "UserID" {
+		t.Errorf("expected User_Id to be renamed to UserID, got %s", to)
+	}
+	if to := r.nameOf(lookupDef(pkg, "Get_Name")); to != "Get_Name" {
+		t.Errorf("expected Get_Name to be kept, got %s", to)
+	}
+}
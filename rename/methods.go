@@ -0,0 +1,309 @@
+package rename
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+	"sort"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// SetProtected sets a predicate for the objects that may not be renamed
+// unless asked to, such as exported API. A rename that would have to
+// rename a protected object along with it is refused.
+func (r *Renamer) SetProtected(protected func(obj types.Object) bool) {
+	r.protected = protected
+}
+
+// Group returns the objects renamed together with obj, obj included,
+// once Check or Update has run.
+func (r *Renamer) Group(obj types.Object) []types.Object {
+	var objs []types.Object
+	for _, key := range r.groups[r.key(obj)] {
+		if o := r.objectsOf(key); len(o) > 0 {
+			objs = append(objs, o[0])
+		}
+	}
+	if len(objs) == 0 {
+		objs = []types.Object{obj}
+	}
+	return objs
+}
+
+// suppressed reports whether a //fixname:ignore or //nolint directive
+// suppresses renaming obj, declared in an initial package, in category.
+func (r *Renamer) suppressed(obj types.Object, category lint.Category) bool {
+	if r.directives == nil {
+		r.directives = make(map[*token.File]*lint.Directives)
+		for _, pkg := range r.pkgs {
+			for _, f := range pkg.Syntax {
+				if tf := r.fset.File(f.Pos()); r.directives[tf] == nil {
+					r.directives[tf] = lint.ParseDirectives(r.fset, f)
+				}
+			}
+		}
+	}
+	ds := r.directives[r.fset.File(obj.Pos())]
+	return ds != nil && ds.Suppresses(obj.Pos(), category)
+}
+
+// A methodTypes holds the candidates for implementing each other: the
+// named types declared in the initial packages, and the named
+// interfaces declared in them or in the packages they import, directly
+// or not. Whether a generic type implements an interface depends on its
+// type arguments, so generic types are kept apart.
+type methodTypes struct {
+	concrete, ifaces []*types.Named
+	generic          []*types.Named
+}
+
+func (r *Renamer) methodTypes() *methodTypes {
+	initial := make(map[*types.Package]bool)
+	for _, pkg := range r.pkgs {
+		initial[pkg.Types] = true
+	}
+
+	mt := new(methodTypes)
+	seen := make(map[*types.Package]bool)
+	var addScope func(pkg *types.Package)
+	addScope = func(pkg *types.Package) {
+		if seen[pkg] {
+			return
+		}
+		seen[pkg] = true
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || (!initial[pkg] && !types.IsInterface(named)) {
+				continue
+			}
+			switch {
+			case named.TypeParams().Len() > 0:
+				mt.generic = append(mt.generic, named)
+			case types.IsInterface(named):
+				mt.ifaces = append(mt.ifaces, named)
+			default:
+				mt.concrete = append(mt.concrete, named)
+			}
+		}
+		for _, imp := range pkg.Imports() {
+			addScope(imp)
+		}
+	}
+	for _, pkg := range r.pkgs {
+		addScope(pkg.Types)
+	}
+	return mt
+}
+
+// implements reports whether t or, unless t is an interface, *t
+// implements iface.
+func implements(t types.Type, iface types.Type) bool {
+	i := iface.Underlying().(*types.Interface)
+	if types.IsInterface(t) {
+		return types.Implements(t, i)
+	}
+	return types.Implements(t, i) || types.Implements(types.NewPointer(t), i)
+}
+
+// methodGroup returns the methods that must be renamed together with m,
+// m included, so that every type keeps implementing the interfaces it
+// implements: the methods of the interfaces m implements an interface
+// method of, and the methods implementing m if m is one.
+func (r *Renamer) methodGroup(m *types.Func, mt *methodTypes) []*types.Func {
+	concrete, ifaces := mt.concrete, mt.ifaces
+	lookup := methodLookup(m)
+
+	seen := make(map[objKey]bool)
+	var group []*types.Func
+	var add func(f *types.Func)
+	add = func(f *types.Func) {
+		if f == nil || seen[r.key(f)] {
+			return
+		}
+		seen[r.key(f)] = true
+		group = append(group, f)
+
+		recv := f.Type().(*types.Signature).Recv().Type()
+		if types.IsInterface(recv) {
+			// The implementations of the interface method, and the
+			// methods of the interfaces convertible to or from its
+			// interface.
+			for _, t := range concrete {
+				if implements(t, recv) {
+					add(lookup(t))
+				}
+			}
+			for _, i := range ifaces {
+				if implements(i, recv) || implements(recv, i) {
+					add(lookup(i))
+				}
+			}
+			return
+		}
+		// The interfaces the types having f in their method sets
+		// implement with it.
+		for _, t := range concrete {
+			if g := lookup(t); g == nil || !r.same(g, f) {
+				continue
+			}
+			for _, i := range ifaces {
+				if lookup(i) != nil && implements(t, i) {
+					add(lookup(i))
+				}
+			}
+		}
+	}
+	add(m)
+	return group
+}
+
+// methodLookup returns a function looking up the method named like m in
+// the method set of a type.
+func methodLookup(m *types.Func) func(t types.Type) *types.Func {
+	return func(t types.Type) *types.Func {
+		obj, _, _ := types.LookupFieldOrMethod(t, true, m.Pkg(), m.Name())
+		f, _ := obj.(*types.Func)
+		return f
+	}
+}
+
+// genericLink returns a generic type with a method named like m that
+// group, the method group of m, may be linked to through its instances:
+// a generic interface, the generic type declaring m if any interface has
+// such a method, or any generic type if group has an interface method.
+// Type arguments are not tracked, so such a group is refused.
+func (r *Renamer) genericLink(m *types.Func, group []*types.Func, mt *methodTypes) *types.Named {
+	lookup := methodLookup(m)
+	var inGroup, anyIface bool
+	for _, f := range group {
+		if types.IsInterface(f.Type().(*types.Signature).Recv().Type()) {
+			inGroup = true
+		}
+	}
+	anyIface = inGroup
+	for _, i := range mt.ifaces {
+		if lookup(i) != nil {
+			anyIface = true
+		}
+	}
+	for _, g := range mt.generic {
+		f := lookup(g)
+		switch {
+		case f == nil:
+		case types.IsInterface(g), inGroup, anyIface && r.same(f, m):
+			return g
+		}
+	}
+	return nil
+}
+
+// expandGroups adds the renames of the objects that must be renamed
+// together with the pending renames, the methods linked by interfaces
+// and the type names and the fields embedding them, or refuses the
+// whole group if that is not possible, because a member is declared
+// outside the initial packages, suppressed by a directive or protected,
+// and returns the reasons.
+func (r *Renamer) expandGroups() []*Conflict {
+	var keys []objKey
+	for key := range r.objsToUpdate {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pos.Filename != keys[j].pos.Filename {
+			return keys[i].pos.Filename < keys[j].pos.Filename
+		}
+		return keys[i].pos.Offset < keys[j].pos.Offset
+	})
+
+	r.groups = make(map[objKey][]objKey)
+	var mt *methodTypes
+	var embedded map[objKey][]*types.Var
+	var conflicts []*Conflict
+	for _, key := range keys {
+		spec, ok := r.objsToUpdate[key]
+		if !ok || r.groups[key] != nil {
+			continue
+		}
+		objs := r.objectsOf(key)
+		if len(objs) == 0 {
+			continue
+		}
+		obj := objs[0]
+
+		var group []types.Object
+		var c *Conflict
+		if m, ok := obj.(*types.Func); ok && m.Type().(*types.Signature).Recv() != nil {
+			if mt == nil {
+				mt = r.methodTypes()
+			}
+			methods := r.methodGroup(m, mt)
+			for _, f := range methods {
+				group = append(group, f)
+			}
+			if g := r.genericLink(m, methods, mt); g != nil {
+				c = r.conflict(obj, spec.To, "may require renaming the methods of the generic type %s declared at %s, whose interfaces are not tracked",
+					g.Obj().Name(), r.position(g.Obj().Pos()))
+			}
+		} else {
+			if embedded == nil {
+				embedded = r.embeddedFields()
+			}
+			group = r.embeddedGroup(obj, embedded)
+		}
+		if len(group) == 0 {
+			continue
+		}
+
+		for _, f := range group {
+			if c != nil {
+				break
+			}
+			other, pending := r.objsToUpdate[r.key(f)]
+			switch {
+			case r.same(f, obj):
+			case pending && other.To != spec.To:
+				c = r.conflict(obj, spec.To, "would also require renaming %s declared at %s, which is renamed to %q instead",
+					describe(f), r.position(f.Pos()), other.To)
+			case pending:
+			case r.packageOf(f) == nil:
+				c = r.conflict(obj, spec.To, "would also require renaming %s declared in package %s at %s",
+					describe(f), f.Pkg().Path(), r.position(f.Pos()))
+			case r.suppressed(f, spec.Category):
+				c = r.conflict(obj, spec.To, "would also require renaming %s declared at %s, which is suppressed by a directive",
+					describe(f), r.position(f.Pos()))
+			case r.protected != nil && r.protected(f):
+				c = r.conflict(obj, spec.To, "would also require renaming %s declared at %s, which is protected",
+					describe(f), r.position(f.Pos()))
+			}
+		}
+
+		var groupKeys []objKey
+		for _, f := range group {
+			groupKeys = append(groupKeys, r.key(f))
+		}
+		if c != nil {
+			conflicts = append(conflicts, c)
+			for _, k := range groupKeys {
+				delete(r.objsToUpdate, k)
+			}
+			continue
+		}
+		for _, f := range group {
+			k := r.key(f)
+			if _, ok := r.objsToUpdate[k]; !ok {
+				if r.verbose {
+					log.Printf("%s: renaming %s along with %s", r.position(f.Pos()), describe(f), describe(obj))
+				}
+				r.objsToUpdate[k] = lint.Spec{Id: spec.Id, To: spec.To, Category: spec.Category}
+			}
+			r.groups[k] = groupKeys
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,241 @@
+package rename
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// lookupMethod returns the method name of the type named typ.
+func lookupMethod(pkg *packages.Package, typ, name string) types.Object {
+	obj, _, _ := types.LookupFieldOrMethod(pkg.Types.Scope().Lookup(typ).Type(), true, pkg.Types, name)
+	return obj
+}
+
+func TestMethodGroups(t *testing.T) {
+	testData := []struct {
+		name      string
+		src       string
+		rename    [][2]string // type and method
+		protected string
+		expected  []string // renamed methods, as type.method
+		conflict  string
+	}{
+		{
+			name: "interface method",
+			src: `package p
+type I interface{ Get_Value() int }
+type J interface{ I; Close() }
+type T struct{}
+func (T) Get_Value() int { return 0 }
+type U struct{}
+func (*U) Get_Value() int { return 0 }
+type V struct{}
+func (V) Get_Value() string { return "" }`,
+			rename:   [][2]string{{"I", "Get_Value"}},
+			expected: []string{"I.Get_Value", "T.Get_Value", "U.Get_Value"},
+		},
+		{
+			name: "concrete method",
+			src: `package p
+type I interface{ Get_Value() int }
+type K interface{ Get_Value() int; Close() }
+type T struct{}
+func (T) Get_Value() int { return 0 }
+type W struct{ T }`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			expected: []string{"I.Get_Value", "K.Get_Value", "T.Get_Value"},
+		},
+		{
+			name: "type without the method",
+			src: `package p
+type I interface{ Get_Value() int }
+type T struct{}
+func (T) Get_Value() int { return 0 }
+type E struct{}
+func (E) Error() string { return "" }`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			expected: []string{"I.Get_Value", "T.Get_Value"},
+		},
+		{
+			name: "dependency interface",
+			src: `package p
+import "example.com/dep"
+type T struct{}
+func (T) Get_Value() int { return 0 }
+var _ dep.Getter = T{}`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			conflict: `would also require renaming method "Get_Value" declared in package example.com/dep`,
+		},
+		{
+			name: "protected",
+			src: `package p
+type I interface{ Get_Value() int }
+type t struct{}
+func (t) Get_Value() int { return 0 }`,
+			rename:    [][2]string{{"t", "Get_Value"}},
+			protected: "I",
+			conflict:  `which is protected`,
+		},
+		{
+			name: "conflicting member",
+			src: `package p
+type I interface{ Get_Value() int }
+type T struct{}
+func (T) Get_Value() int { return 0 }
+type U struct{ GetValue int }
+func (U) Get_Value() int { return 0 }`,
+			rename:   [][2]string{{"I", "Get_Value"}},
+			conflict: `conflicts with field "GetValue"`,
+		},
+		{
+			name: "generic implementation",
+			src: `package p
+type I interface{ Get_Value() int }
+type G[T any] struct{}
+func (G[T]) Get_Value() int { return 0 }
+var _ I = G[int]{}`,
+			rename:   [][2]string{{"I", "Get_Value"}},
+			conflict: `the generic type G declared at`,
+		},
+		{
+			name: "method of a generic type",
+			src: `package p
+type I interface{ Get_Value() int }
+type G[T any] struct{}
+func (G[T]) Get_Value() T { var v T; return v }
+var _ I = G[int]{}`,
+			rename:   [][2]string{{"G", "Get_Value"}},
+			conflict: `the generic type G declared at`,
+		},
+		{
+			name: "generic interface",
+			src: `package p
+type I[T any] interface{ Get_Value() T }
+type T struct{}
+func (T) Get_Value() int { return 0 }
+var _ I[int] = T{}`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			conflict: `the generic type I declared at`,
+		},
+		{
+			name: "generic type without interfaces",
+			src: `package p
+type G[T any] struct{}
+func (G[T]) Get_Value() int { return 0 }
+type T struct{}
+func (T) Get_Value() int { return 0 }`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			expected: []string{"T.Get_Value"},
+		},
+		{
+			name: "suppressed member",
+			src: `package p
+type I interface {
+	Get_Value() int //fixname:ignore
+}
+type T struct{}
+func (T) Get_Value() int { return 0 }`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			conflict: `which is suppressed by a directive`,
+		},
+		{
+			name: "member suppressed in another category",
+			src: `package p
+type I interface {
+	Get_Value() int //nolint:golint
+}
+type J interface {
+	//fixname:ignore caps
+	Get_Value() int
+}
+type T struct{}
+func (T) Get_Value() int { return 0 }`,
+			rename:   [][2]string{{"T", "Get_Value"}},
+			expected: []string{"I.Get_Value", "J.Get_Value", "T.Get_Value"},
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkg := loadTestPackage(t, tt.src)
+		r := New(fset, []*packages.Package{pkg})
+		if tt.protected != "" {
+			r.SetProtected(func(obj types.Object) bool {
+				return r.same(obj, lookupMethod(pkg, tt.protected, obj.Name()))
+			})
+		}
+		for _, m := range tt.rename {
+			obj := lookupMethod(pkg, m[0], m[1])
+			spec := lint.Check(&ast.Ident{Name: m[1]})
+			r.Rename(obj, *spec)
+		}
+
+		conflicts := r.checkConflicts()
+		if tt.conflict != "" {
+			if len(conflicts) == 0 || !strings.Contains(conflicts[0].Error(), tt.conflict) {
+				t.Errorf("Test: %s, expected conflict %q, got: %v", tt.name, tt.conflict, conflicts)
+			}
+			if len(r.objsToUpdate) != 0 {
+				t.Errorf("Test: %s, expected the whole group to be refused, got %d renames", tt.name, len(r.objsToUpdate))
+			}
+			continue
+		}
+		if len(conflicts) != 0 {
+			t.Errorf("Test: %s, unexpected conflict: %v", tt.name, conflicts[0])
+			continue
+		}
+
+		var actual []string
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if m := lookupMethod(pkg, name, "Get_Value"); m != nil && r.nameOf(m) == "GetValue" {
+				// report promoted methods under their declaring type only
+				if owner(m) == name {
+					actual = append(actual, tn.Name()+"."+m.Name())
+				}
+			}
+		}
+		sort.Strings(actual)
+		if strings.Join(actual, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestMethodGroupsTransitiveImport(t *testing.T) {
+	// app reaches the interface of iface only through mid.
+	fset, pkgs := loadTestPackages(t, [][2]string{
+		{"example.com/iface", `package iface
+type Getter interface{ Get_Value() int }
+`},
+		{"example.com/mid", `package mid
+import "example.com/iface"
+func Use(g iface.Getter) int { return g.Get_Value() }
+`},
+		{"example.com/app", `package app
+import "example.com/mid"
+type T struct{}
+func (T) Get_Value() int { return 0 }
+var _ = mid.Use(T{})
+`},
+	})
+	app := pkgs[2]
+	r := New(fset, []*packages.Package{app})
+	r.Rename(lookupMethod(app, "T", "Get_Value"), *lint.Check(&ast.Ident{Name: "Get_Value"}))
+
+	conflicts := r.checkConflicts()
+	expected := `would also require renaming method "Get_Value" declared in package example.com/iface`
+	if len(conflicts) == 0 || !strings.Contains(conflicts[0].Error(), expected) {
+		t.Errorf("expected conflict %q, got: %v", expected, conflicts)
+	}
+}
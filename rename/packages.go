@@ -0,0 +1,157 @@
+package rename
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// RenamePackage schedules renaming the package pkg. Update rewrites the
+// package clauses of its files and, in the initial packages importing
+// it, the references to the package name its imports declare. If any of
+// them cannot be renamed, none of them is. The external test package of
+// pkg, if named after it, is renamed along with it.
+func (r *Renamer) RenamePackage(pkg *types.Package, spec lint.Spec) {
+	r.packages[pkg.Path()] = spec
+}
+
+// PendingPackage reports whether a rename of the package with the given
+// path is pending, that is, has been requested and not refused so far.
+func (r *Renamer) PendingPackage(path string) bool {
+	_, ok := r.packages[path]
+	return ok
+}
+
+// packageName returns the name that pkg will have once the pending
+// renames are applied.
+func (r *Renamer) packageName(pkg *types.Package) string {
+	if spec, ok := r.packages[pkg.Path()]; ok {
+		return spec.To
+	}
+	return pkg.Name()
+}
+
+// IsAPIPackage reports whether renaming pkg may break importers outside
+// the loaded program, that is whether pkg can be imported at all.
+func (r *Renamer) IsAPIPackage(pkg *types.Package) bool {
+	return pkg.Name() != "main" && !strings.HasSuffix(pkg.Path(), "_test")
+}
+
+// importNames returns the package names declared implicitly by the
+// imports of the package with the given path in the initial packages,
+// that is by those without an alias.
+func (r *Renamer) importNames(path string) []*types.PkgName {
+	seen := make(map[objKey]bool)
+	var names []*types.PkgName
+	for _, pkg := range r.pkgs {
+		for _, f := range pkg.Syntax {
+			for _, imp := range f.Imports {
+				pn, ok := pkg.TypesInfo.Implicits[imp].(*types.PkgName)
+				if !ok || imp.Name != nil || pn.Imported().Path() != path || seen[r.key(pn)] {
+					continue
+				}
+				seen[r.key(pn)] = true
+				names = append(names, pn)
+			}
+		}
+	}
+	return names
+}
+
+// expandPackages schedules the renames of the package names declared
+// by the imports of the renamed packages, as a group per package, and
+// of their external test packages. It must be called after
+// expandGroups.
+func (r *Renamer) expandPackages() {
+	var paths []string
+	for path := range r.packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	r.packageKeys = make(map[string][]objKey)
+	for _, path := range paths {
+		spec := r.packages[path]
+		var name string
+		for _, pkg := range r.pkgs {
+			if pkg.PkgPath == path {
+				name = pkg.Name
+			}
+		}
+		xtest := path + "_test"
+		for _, pkg := range r.pkgs {
+			if _, ok := r.packages[xtest]; !ok && name != "" && pkg.PkgPath == xtest && pkg.Name == name+"_test" {
+				r.packages[xtest] = lint.Spec{Id: spec.Id, To: spec.To + "_test", Category: spec.Category}
+			}
+		}
+
+		var keys []objKey
+		names := r.importNames(path)
+		for _, pn := range names {
+			keys = append(keys, r.key(pn))
+		}
+		for _, pn := range names {
+			k := r.key(pn)
+			if _, ok := r.objsToUpdate[k]; !ok {
+				r.objsToUpdate[k] = lint.Spec{Id: spec.Id, To: spec.To, Category: spec.Category}
+			}
+			r.groups[k] = keys
+		}
+		r.packageKeys[path] = keys
+	}
+}
+
+// dropRefusedPackages cancels the package renames for which the rename
+// of a package name in an importer was refused.
+func (r *Renamer) dropRefusedPackages() {
+	for path, keys := range r.packageKeys {
+		for _, k := range keys {
+			if _, ok := r.objsToUpdate[k]; ok {
+				continue
+			}
+			if r.verbose {
+				log.Printf("not renaming package %s, as its importer at %s cannot refer to it by the new name", path, k.pos)
+			}
+			delete(r.packages, path)
+			delete(r.packages, path+"_test")
+			break
+		}
+	}
+}
+
+// PackageOccurrences returns the positions of the package clauses of the
+// package with the given path and of the references to it through the
+// package names its imports declare, in order.
+func (r *Renamer) PackageOccurrences(path string) []token.Position {
+	seen := make(map[token.Position]bool)
+	var positions []token.Position
+	add := func(pos token.Position) {
+		if !seen[pos] {
+			seen[pos] = true
+			positions = append(positions, pos)
+		}
+	}
+	for _, pkg := range r.pkgs {
+		if pkg.PkgPath == path {
+			for _, f := range pkg.Syntax {
+				add(r.position(f.Name.Pos()))
+			}
+		}
+	}
+	for _, pn := range r.importNames(path) {
+		for _, pos := range r.Occurrences(pn) {
+			add(pos)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Filename != positions[j].Filename {
+			return positions[i].Filename < positions[j].Filename
+		}
+		return positions[i].Offset < positions[j].Offset
+	})
+	return positions
+}
@@ -0,0 +1,206 @@
+package rename
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+const utilSrc = `// Package my_util provides helpers.
+package my_util
+
+func Helper() int { return 1 }
+`
+
+func TestRenamePackage(t *testing.T) {
+	testData := []struct {
+		name     string
+		app      string
+		expected map[string]string
+	}{
+		{
+			name: "importer",
+			app: `package app
+
+import "example.com/my_util"
+
+func f() int { return my_util.Helper() }
+`,
+			expected: map[string]string{
+				"/src/example.com/my_util/my_util.go": `// Package myutil provides helpers.
+package myutil
+
+func Helper() int { return 1 }
+`,
+				"/src/example.com/app/app.go": `package app
+
+import "example.com/my_util"
+
+func f() int { return myutil.Helper() }
+`,
+			},
+		},
+		{
+			name: "aliased import",
+			app: `package app
+
+import util "example.com/my_util"
+
+func f() int { return util.Helper() }
+`,
+			expected: map[string]string{
+				"/src/example.com/my_util/my_util.go": `// Package myutil provides helpers.
+package myutil
+
+func Helper() int { return 1 }
+`,
+			},
+		},
+		{
+			name: "conflict in importer",
+			app: `package app
+
+import "example.com/my_util"
+
+var myutil = 1
+
+func f() int { return my_util.Helper() + myutil }
+`,
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkgs := loadTestPackages(t, [][2]string{
+			{"example.com/my_util", utilSrc},
+			{"example.com/app", tt.app},
+		})
+		r := New(fset, pkgs)
+		util := pkgs[0]
+		spec := lint.CheckPackageName(util.Syntax[0].Name)
+		r.RenamePackage(util.Types, *spec)
+
+		actual, err := update(r)
+		if len(tt.expected) == 0 && err == nil {
+			t.Errorf("Test: %s, expected the rename to be refused", tt.name)
+		}
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestRenameImportAlias(t *testing.T) {
+	testData := []struct {
+		name     string
+		app      string
+		pkg      bool
+		expected string
+	}{
+		{
+			name: "package name",
+			app: `package app
+
+import str_conv "example.com/strconv"
+
+func f() string { return str_conv.Itoa(1) }
+`,
+			expected: `package app
+
+import "example.com/strconv"
+
+func f() string { return strconv.Itoa(1) }
+`,
+		},
+		{
+			name: "other name",
+			app: `package app
+
+import str_util "example.com/strconv"
+
+func f() string { return str_util.Itoa(1) }
+`,
+			expected: `package app
+
+import strutil "example.com/strconv"
+
+func f() string { return strutil.Itoa(1) }
+`,
+		},
+		{
+			name: "renamed package",
+			app: `package app
+
+import my_util "example.com/my_util"
+
+func f() int { return my_util.Helper() }
+`,
+			pkg: true,
+			expected: `package app
+
+import "example.com/my_util"
+
+func f() int { return myutil.Helper() }
+`,
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkgs := loadTestPackages(t, [][2]string{
+			{"example.com/strconv", "package strconv\n\nfunc Itoa(i int) string { return \"\" }\n"},
+			{"example.com/my_util", utilSrc},
+			{"example.com/app", tt.app},
+		})
+		r := New(fset, pkgs)
+		if tt.pkg {
+			spec := lint.CheckPackageName(pkgs[1].Syntax[0].Name)
+			r.RenamePackage(pkgs[1].Types, *spec)
+		}
+		renameChecked(r, pkgs[2])
+
+		actual, err := update(r)
+		if err != nil {
+			t.Errorf("Test: %s, %v", tt.name, err)
+		}
+		if app := actual["/src/example.com/app/app.go"]; app != tt.expected {
+			t.Errorf("Test: %s, expected: %s, got: %s", tt.name, tt.expected, app)
+		}
+	}
+}
+
+func TestPackageManifest(t *testing.T) {
+	app := `package app
+
+import "example.com/my_util"
+
+func f() int { return my_util.Helper() }
+`
+	fset, pkgs := loadTestPackages(t, [][2]string{
+		{"example.com/my_util", utilSrc},
+		{"example.com/app", app},
+	})
+
+	r := New(fset, pkgs)
+	r.RenamePackage(pkgs[0].Types, *lint.CheckPackageName(pkgs[0].Syntax[0].Name))
+	r.Check()
+	entries := r.Manifest()
+	expected := []ManifestEntry{{Package: "example.com/my_util", Old: "my_util", New: "myutil", Kind: "package"}}
+	if !reflect.DeepEqual(expected, entries) {
+		t.Fatalf("expected: %v, got: %v", expected, entries)
+	}
+
+	// Apply the manifest to the importer alone.
+	r = New(fset, pkgs[1:])
+	if n := r.Apply(entries); n != 1 {
+		t.Errorf("expected 1 match, got %d", n)
+	}
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := contents["/src/example.com/app/app.go"]; !strings.Contains(actual, "return myutil.Helper()") {
+		t.Errorf("expected the qualifier to be renamed, got:\n%s", actual)
+	}
+}
@@ -5,32 +5,83 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/token"
 	"go/types"
 	"log"
 	"sort"
 
-	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
 
 	"github.com/knzm/go-fixname/lint"
 )
 
+// An objKey identifies an object independently of the package variant
+// it was type-checked in. go/packages type-checks a package and its
+// test variant separately, so the same declaration yields a distinct
+// types.Object in each; they share a name and a source position. So do
+// the fields and methods of the instances of a generic type, recorded
+// in Uses and Selections, with those of the generic type itself.
+type objKey struct {
+	pos  token.Position
+	name string
+}
+
 type Renamer struct {
-	iprog        *loader.Program
-	objsToUpdate map[types.Object]lint.Spec
-	verbose      bool
-	quiet        bool
-	writeFunc    func(filename string, content []byte) error
+	fset            *token.FileSet
+	pkgs            []*packages.Package
+	objsToUpdate    map[objKey]lint.Spec
+	verbose         bool
+	quiet           bool
+	aliases         bool
+	tags            map[string]bool
+	rewriteStrings  bool
+	rewriteComments bool
+	writeFunc       func(filename string, content []byte) error
+	verifyFunc      func(contents map[string][]byte) error
+	protected       func(obj types.Object) bool
+
+	// objects renamed together, by the key of each
+	groups map[objKey][]objKey
+
+	// the renames refused so far
+	conflicts []*Conflict
+
+	// package renames by path, and the keys of the package names
+	// their importers declare
+	packages    map[string]lint.Spec
+	packageKeys map[string][]objKey
+
+	// lazily built indexes
+	byTypes      map[*types.Package]*packages.Package
+	objects      map[objKey][]types.Object
+	positions    map[objKey][]token.Position
+	directives   map[*token.File]*lint.Directives
+	uses         map[*packages.Package]*useIndex
+	structs      map[*types.Var]*types.Struct
+	namedStructs map[*types.Struct][]*types.Named
+
+	// names by which checkConflicts looks up objects in a round
+	names *nameIndex
 }
 
-func New(iprog *loader.Program) *Renamer {
+// New returns a Renamer for the given initial packages, which must all
+// have been loaded with syntax and type information into fset.
+func New(fset *token.FileSet, pkgs []*packages.Package) *Renamer {
+	sorted := make([]*packages.Package, len(pkgs))
+	copy(sorted, pkgs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
 	return &Renamer{
-		iprog:        iprog,
-		objsToUpdate: make(map[types.Object]lint.Spec),
+		fset:         fset,
+		pkgs:         sorted,
+		objsToUpdate: make(map[objKey]lint.Spec),
+		packages:     make(map[string]lint.Spec),
 	}
 }
 
 func (r *Renamer) Rename(obj types.Object, spec lint.Spec) {
-	r.objsToUpdate[obj] = spec
+	r.objsToUpdate[r.key(obj)] = spec
 }
 
 func (r *Renamer) Update() error {
@@ -39,94 +90,298 @@ func (r *Renamer) Update() error {
 		writeFunc = WriteFile
 	}
 
-	infolist := make([]*loader.PackageInfo, 0, len(r.iprog.Imported))
-	for _, info := range r.iprog.Imported {
-		infolist = append(infolist, info)
+	conflicts := r.checkConflicts()
+	for _, c := range conflicts {
+		log.Print(c)
 	}
-	for _, info := range r.iprog.Created {
-		infolist = append(infolist, info)
+	r.preserveKeys()
+	refs, rewritten, templates := r.scanStrings()
+	for _, ref := range refs {
+		log.Print(ref)
 	}
-	sort.Slice(infolist, func(i, j int) bool {
-		return infolist[i].Pkg.Path() < infolist[j].Pkg.Path()
-	})
-
-	var nidents int
-	filesToUpdate := make(map[string]*ast.File)
-	for _, info := range infolist {
-		astfileMap := make(map[string]*ast.File)
-		for _, f := range info.Files {
-			filename := r.iprog.Fset.File(f.Pos()).Name()
-			astfileMap[filename] = f
-		}
 
+	// Occurrences are counted by position, as a file shared by several
+	// variants of a package is processed once per variant.
+	occurrences := make(map[token.Position]bool)
+	filesToUpdate := make(map[string]bool)
+	for filename := range rewritten {
+		filesToUpdate[filename] = true
+	}
+	for filename := range r.updateComments() {
+		filesToUpdate[filename] = true
+	}
+	for _, pkg := range r.pkgs {
 		processObjects := func(m map[*ast.Ident]types.Object) {
 			for id, obj := range m {
-				if spec, ok := r.objsToUpdate[obj]; ok {
-					filename := r.iprog.Fset.File(id.Pos()).Name()
-					filesToUpdate[filename] = astfileMap[filename]
+				if obj == nil {
+					continue
+				}
+				if spec, ok := r.objsToUpdate[r.key(obj)]; ok {
+					filename := r.fset.File(id.Pos()).Name()
+					filesToUpdate[filename] = true
 					id.Name = spec.To
-					nidents++
+					occurrences[r.fset.Position(id.Pos())] = true
 				}
 			}
 		}
 
-		processObjects(info.Info.Defs)
-		processObjects(info.Info.Uses)
-	}
+		processObjects(pkg.TypesInfo.Defs)
+		processObjects(pkg.TypesInfo.Uses)
+		processObjects(symbolicIdents(pkg))
 
-	var nerrs, npkgs int
-	for _, info := range infolist {
-		files := make([]*ast.File, len(info.Files))
-		for i, f := range info.Files {
-			files[i] = f
+		// An import alias renamed to the name of the package it imports
+		// is redundant, so the import is left without one.
+		for _, f := range pkg.Syntax {
+			for _, imp := range f.Imports {
+				if imp.Name == nil {
+					continue
+				}
+				pn, ok := pkg.TypesInfo.Defs[imp.Name].(*types.PkgName)
+				if !ok {
+					continue
+				}
+				if spec, ok := r.objsToUpdate[r.key(pn)]; ok && spec.To == r.packageName(pn.Imported()) {
+					imp.Name = nil
+				}
+			}
 		}
+
+		if spec, ok := r.packages[pkg.PkgPath]; ok {
+			for _, f := range pkg.Syntax {
+				if f.Doc != nil {
+					for _, c := range f.Doc.List {
+						c.Text = replaceWord(c.Text, f.Name.Name, spec.To)
+					}
+				}
+				filesToUpdate[r.fset.File(f.Pos()).Name()] = true
+				occurrences[r.fset.Position(f.Name.Pos())] = true
+				f.Name.Name = spec.To
+			}
+		}
+	}
+
+	var nerrs int
+	var filenames []string
+	pkgsToUpdate := make(map[string]bool)
+	contents := make(map[string][]byte)
+	for _, pkg := range r.pkgs {
+		files := make([]*ast.File, len(pkg.Syntax))
+		copy(files, pkg.Syntax)
 		sort.Slice(files, func(i, j int) bool {
 			return files[i].Pos() < files[j].Pos()
 		})
 
-		first := true
+		goFiles := make(map[string]bool)
+		for _, filename := range pkg.GoFiles {
+			goFiles[filename] = true
+		}
+
 		for _, f := range files {
-			filename := r.iprog.Fset.File(f.Pos()).Name()
-			if filesToUpdate[filename] == nil {
+			filename := r.fset.File(f.Pos()).Name()
+			if !filesToUpdate[filename] || !goFiles[filename] {
+				// unchanged, or generated by cgo
+				continue
+			}
+			if _, ok := contents[filename]; ok {
+				// already formatted from another variant of the package
 				continue
 			}
 
-			if first {
-				npkgs++
-				first = false
+			if !pkgsToUpdate[pkg.PkgPath] {
+				pkgsToUpdate[pkg.PkgPath] = true
 				if r.verbose {
-					log.Printf("Updating package %s", info.Pkg.Path())
+					log.Printf("Updating package %s", pkg.PkgPath)
 				}
 			}
 
 			var buf bytes.Buffer
-			if err := format.Node(&buf, r.iprog.Fset, f); err != nil {
+			if err := format.Node(&buf, r.fset, f); err != nil {
 				log.Printf("failed to pretty-print syntax tree: %v", err)
 				nerrs++
 				continue
 			}
+			filenames = append(filenames, filename)
+			contents[filename] = buf.Bytes()
+		}
+	}
 
-			if err := writeFunc(filename, buf.Bytes()); err != nil {
-				log.Print(err)
-				nerrs++
-				continue
-			}
+	var added []string
+	for filename, content := range templates {
+		added = append(added, filename)
+		contents[filename] = content
+	}
+	sort.Strings(added)
+	filenames = append(filenames, added...)
+
+	if r.aliases {
+		files := r.aliasFiles()
+		added = nil
+		for filename, content := range files {
+			added = append(added, filename)
+			contents[filename] = content
+		}
+		sort.Strings(added)
+		filenames = append(filenames, added...)
+	}
+
+	// Type-check the renamed program before touching anything, so
+	// that a broken rename leaves the original files as they were.
+	if r.verifyFunc != nil && len(contents) > 0 {
+		if r.verbose {
+			log.Printf("Verifying %s", plural(len(contents), "file", "files"))
+		}
+		if err := r.verifyFunc(contents); err != nil {
+			return err
+		}
+	}
+
+	for _, filename := range filenames {
+		if err := writeFunc(filename, contents[filename]); err != nil {
+			log.Print(err)
+			nerrs++
+			continue
 		}
 	}
 	if !r.quiet {
 		log.Printf("Renamed %s in %s in %s.",
-			plural(nidents, "occurrence", "occurrences"),
-			plural(len(filesToUpdate), "file", "files"),
-			plural(npkgs, "package", "packages"))
+			plural(len(occurrences), "occurrence", "occurrences"),
+			plural(len(filenames), "file", "files"),
+			plural(len(pkgsToUpdate), "package", "packages"))
 	}
 
 	if nerrs > 0 {
 		return fmt.Errorf("failed to rewrite %s", plural(nerrs, "file", "files"))
 	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("refused %s due to conflicts", plural(len(conflicts), "rename", "renames"))
+	}
 
 	return nil
 }
 
+// Occurrences returns the positions of every identifier in the initial
+// packages that refers to obj, including its declaration, in order.
+// The positions of all objects are indexed in a single pass on the
+// first call.
+func (r *Renamer) Occurrences(obj types.Object) []token.Position {
+	if r.positions == nil {
+		r.positions = make(map[objKey][]token.Position)
+		// An embedded field refers to both the field and its type,
+		// so positions are deduplicated per object.
+		type occurrence struct {
+			key objKey
+			pos token.Position
+		}
+		seen := make(map[occurrence]bool)
+		for _, pkg := range r.pkgs {
+			for _, m := range []map[*ast.Ident]types.Object{pkg.TypesInfo.Defs, pkg.TypesInfo.Uses, symbolicIdents(pkg)} {
+				for id, obj := range m {
+					if obj == nil {
+						continue
+					}
+					o := occurrence{r.key(obj), r.fset.Position(id.Pos())}
+					if !seen[o] {
+						seen[o] = true
+						r.positions[o.key] = append(r.positions[o.key], o.pos)
+					}
+				}
+			}
+		}
+		for _, positions := range r.positions {
+			sort.Slice(positions, func(i, j int) bool {
+				if positions[i].Filename != positions[j].Filename {
+					return positions[i].Filename < positions[j].Filename
+				}
+				return positions[i].Offset < positions[j].Offset
+			})
+		}
+	}
+	return r.positions[r.key(obj)]
+}
+
+// GroupOccurrences returns the positions of every identifier in the
+// initial packages that refers to obj or to an object renamed together
+// with it, as found by Check, in order. The identifier of an embedded
+// field, which refers to both the field and its type, is included once.
+func (r *Renamer) GroupOccurrences(obj types.Object) []token.Position {
+	seen := make(map[token.Position]bool)
+	var positions []token.Position
+	for _, o := range r.Group(obj) {
+		for _, pos := range r.Occurrences(o) {
+			if !seen[pos] {
+				seen[pos] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Filename != positions[j].Filename {
+			return positions[i].Filename < positions[j].Filename
+		}
+		return positions[i].Offset < positions[j].Offset
+	})
+	return positions
+}
+
+func (r *Renamer) key(obj types.Object) objKey {
+	return objKey{
+		pos:  r.fset.Position(obj.Pos()),
+		name: obj.Name(),
+	}
+}
+
+// same reports whether a and b denote the same declaration, possibly
+// in different variants of a package.
+func (r *Renamer) same(a, b types.Object) bool {
+	return a == b || (a != nil && b != nil && r.key(a) == r.key(b))
+}
+
+// packageOf returns the initial package that type-checked obj, or nil
+// if obj belongs to a dependency.
+func (r *Renamer) packageOf(obj types.Object) *packages.Package {
+	if r.byTypes == nil {
+		r.byTypes = make(map[*types.Package]*packages.Package)
+		for _, pkg := range r.pkgs {
+			r.byTypes[pkg.Types] = pkg
+		}
+	}
+	return r.byTypes[obj.Pkg()]
+}
+
+// objectsOf returns every object in the initial packages declared at
+// key, one per package variant, and per case clause for the symbolic
+// variable of a type switch, including the package names imports
+// declare implicitly, or else the object of a dependency at key that
+// the initial packages refer to.
+func (r *Renamer) objectsOf(key objKey) []types.Object {
+	if r.objects == nil {
+		r.objects = make(map[objKey][]types.Object)
+		for _, pkg := range r.pkgs {
+			for _, id := range sortedIdents(pkg.TypesInfo.Defs) {
+				if obj := pkg.TypesInfo.Defs[id]; obj != nil {
+					k := r.key(obj)
+					r.objects[k] = append(r.objects[k], obj)
+				}
+			}
+			for _, obj := range implicitObjects(pkg) {
+				k := r.key(obj)
+				r.objects[k] = append(r.objects[k], obj)
+			}
+		}
+		for _, pkg := range r.pkgs {
+			for _, id := range sortedIdents(pkg.TypesInfo.Uses) {
+				if obj := pkg.TypesInfo.Uses[id]; obj != nil && r.packageOf(obj) == nil {
+					k := r.key(obj)
+					if len(r.objects[k]) == 0 {
+						r.objects[k] = []types.Object{obj}
+					}
+				}
+			}
+		}
+	}
+	return r.objects[key]
+}
+
 func (r *Renamer) SetQuiet(quiet bool) {
 	r.quiet = quiet
 }
@@ -138,3 +393,10 @@ func (r *Renamer) SetVerbose(verbose bool) {
 func (r *Renamer) SetWriteFunc(writeFunc func(filename string, content []byte) error) {
 	r.writeFunc = writeFunc
 }
+
+// SetVerifyFunc sets a function that is called with the new contents of
+// every file to be rewritten before any of them is written. If it
+// returns an error, Update aborts without writing anything.
+func (r *Renamer) SetVerifyFunc(verifyFunc func(contents map[string][]byte) error) {
+	r.verifyFunc = verifyFunc
+}
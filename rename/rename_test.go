@@ -0,0 +1,42 @@
+package rename
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestVerifyFailure(t *testing.T) {
+	src := `package p
+
+var user_id int
+`
+	fset, pkg := loadTestPackage(t, src)
+	r := New(fset, []*packages.Package{pkg})
+	if n := renameChecked(r, pkg); n != 1 {
+		t.Fatalf("expected 1 rename, got %d", n)
+	}
+
+	verr := errors.New("does not type-check")
+	var verified map[string][]byte
+	r.SetVerifyFunc(func(contents map[string][]byte) error {
+		verified = contents
+		return verr
+	})
+	var written []string
+	r.SetWriteFunc(func(filename string, content []byte) error {
+		written = append(written, filename)
+		return nil
+	})
+	r.SetQuiet(true)
+	if err := r.Update(); err != verr {
+		t.Errorf("expected: %v, got: %v", verr, err)
+	}
+	if len(verified) != 1 {
+		t.Errorf("expected 1 file to verify, got %d", len(verified))
+	}
+	if written != nil {
+		t.Errorf("expected no writes, got: %v", written)
+	}
+}
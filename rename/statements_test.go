@@ -0,0 +1,180 @@
+package rename
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestStatementNames(t *testing.T) {
+	src := `package p
+
+type Server struct{}
+
+func (my_srv *Server) Run(in chan int, x interface{}) int {
+outer_loop:
+	for {
+		select {
+		case in_val := <-in:
+			_ = in_val
+			break outer_loop
+		}
+	}
+	switch x_val := x.(type) {
+	case int:
+		return x_val
+	case string:
+		return len(x_val)
+	}
+	f := func(user_id int) int { return user_id }
+	return f(0)
+}
+`
+	expected := `package p
+
+type Server struct{}
+
+func (mySrv *Server) Run(in chan int, x interface{}) int {
+outerLoop:
+	for {
+		select {
+		case inVal := <-in:
+			_ = inVal
+			break outerLoop
+		}
+	}
+	switch xVal := x.(type) {
+	case int:
+		return xVal
+	case string:
+		return len(xVal)
+	}
+	f := func(userID int) int { return userID }
+	return f(0)
+}
+`
+	fset, pkg := loadTestPackage(t, src)
+	r := New(fset, []*packages.Package{pkg})
+	if n := renameChecked(r, pkg); n != 5 {
+		t.Errorf("expected 5 names to rename, got %d", n)
+	}
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := contents["/src/p/p.go"]; actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestStatementConflicts(t *testing.T) {
+	testData := []struct {
+		name     string
+		src      string
+		from     string
+		to       string
+		conflict string
+	}{
+		{
+			name: "label vs label",
+			src: `package p
+func f() {
+outer_loop:
+	for {
+		break outer_loop
+	}
+outerLoop:
+	for {
+		break outerLoop
+	}
+}`,
+			from:     "outer_loop",
+			to:       "outerLoop",
+			conflict: `conflicts with label "outerLoop"`,
+		},
+		{
+			name: "label vs var",
+			src: `package p
+func f() {
+	outerLoop := 0
+outer_loop:
+	for {
+		outerLoop++
+		break outer_loop
+	}
+}`,
+			from: "outer_loop",
+			to:   "outerLoop",
+		},
+		{
+			name: "type switch var vs local",
+			src: `package p
+func f(x interface{}) {
+	switch x_val := x.(type) {
+	case int:
+		_ = x_val
+	case string:
+		xVal := 1
+		_, _ = x_val, xVal
+	}
+}`,
+			from:     "x_val",
+			to:       "xVal",
+			conflict: `would be shadowed by var "xVal"`,
+		},
+		{
+			name: "receiver vs parameter",
+			src: `package p
+type Server struct{ n int }
+func (srv *Server) Has(s int) bool {
+	return srv.n == s
+}`,
+			from:     "srv",
+			to:       "s",
+			conflict: `conflicts with var "s"`,
+		},
+		{
+			name: "receiver vs local",
+			src: `package p
+type Server struct{ n int }
+func (srv *Server) Count() int {
+	s := srv.n
+	return s
+}`,
+			from:     "srv",
+			to:       "s",
+			conflict: `conflicts with var "s"`,
+		},
+	}
+
+	for _, tt := range testData {
+		fset, pkg := loadTestPackage(t, tt.src)
+		r := New(fset, []*packages.Package{pkg})
+		var found bool
+		for _, f := range pkg.Syntax {
+			lint.WalkNames(fset, f, nil, func(id *ast.Ident, thing interface{}) {
+				if id.Name == tt.from && !found {
+					found = true
+					r.Rename(NewDefIndex(pkg.TypesInfo).DefOf(id), lint.Spec{Id: id, To: tt.to})
+				}
+			})
+		}
+		if !found {
+			t.Fatalf("Test: %s, %s not found", tt.name, tt.from)
+		}
+
+		conflicts := r.checkConflicts()
+		switch {
+		case tt.conflict == "" && len(conflicts) != 0:
+			t.Errorf("Test: %s, unexpected conflict: %v", tt.name, conflicts[0])
+		case tt.conflict != "" && len(conflicts) == 0:
+			t.Errorf("Test: %s, expected a conflict", tt.name)
+		case tt.conflict != "" && !strings.Contains(conflicts[0].Error(), tt.conflict):
+			t.Errorf("Test: %s, expected: %q, got: %q", tt.name, tt.conflict, conflicts[0].Error())
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TemplateExts are the extensions of the template files in package
+// directories that are scanned for references to renamed names, in
+// addition to the files embedded with //go:embed.
+var TemplateExts = []string{".tmpl", ".tpl", ".gotmpl", ".gohtml"}
+
+// A StringRef is a reference to a renamed name in a string literal or
+// a template file, which the type checker cannot see.
+type StringRef struct {
+	Pos       token.Position
+	Kind      string // "reflect", "template" or "string"
+	Name      string
+	To        string
+	Rewritten bool
+}
+
+func (s *StringRef) String() string {
+	var what string
+	switch s.Kind {
+	case "reflect":
+		what = "reflection"
+	case "template":
+		what = "template"
+	default:
+		what = "string"
+	}
+	msg := fmt.Sprintf("%s: %s refers to %s, which is renamed", s.Pos, what, s.Name)
+	if s.To != "" {
+		msg += " to " + s.To
+	}
+	if s.Rewritten {
+		msg += " (rewritten)"
+	}
+	return msg
+}
+
+// SetRewriteStrings makes Update rewrite the references found in the
+// arguments of reflect's FieldByName and MethodByName and in templates,
+// in Go string literals and template files alike. Other references in
+// string literals are only reported.
+func (r *Renamer) SetRewriteStrings(rewrite bool) {
+	r.rewriteStrings = rewrite
+}
+
+var (
+	identRE    = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	actionRE   = regexp.MustCompile(`(?s){{.*?}}`)
+	templateRE = regexp.MustCompile(`\.[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// stringTargets returns the new names of the pending renames of fields,
+// methods and package-level objects, by old name, and the subset of
+// them that are fields or methods. A name renamed to different names
+// maps to "".
+func (r *Renamer) stringTargets() (all, members map[string]string) {
+	all = make(map[string]string)
+	members = make(map[string]string)
+	add := func(m map[string]string, from, to string) {
+		if prev, ok := m[from]; ok && prev != to {
+			to = ""
+		}
+		m[from] = to
+	}
+	for key, spec := range r.objsToUpdate {
+		objs := r.objectsOf(key)
+		if len(objs) == 0 || r.packageOf(objs[0]) == nil {
+			continue
+		}
+		obj := objs[0]
+		switch kindOf(obj) {
+		case "field", "method":
+			add(members, obj.Name(), spec.To)
+		default:
+			if obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+				continue
+			}
+		}
+		add(all, obj.Name(), spec.To)
+	}
+	return all, members
+}
+
+// rewriteTemplate replaces the field and method references in the
+// actions of a template, calling found for each with its offset.
+func rewriteTemplate(text string, members map[string]string, found func(offset int, name, to string) bool) string {
+	return replaceAll(actionRE, text, 0, func(action string, offset int) string {
+		return replaceAll(templateRE, action, offset, func(ref string, offset int) string {
+			to, ok := members[ref[1:]]
+			if !ok || !found(offset+1, ref[1:], to) {
+				return ref
+			}
+			return "." + to
+		})
+	})
+}
+
+// A literalEdit replaces name at offset in the content of a string
+// literal with to.
+type literalEdit struct {
+	offset   int
+	name, to string
+}
+
+// literalOffsets maps the offset of each byte of the content of the
+// string literal lit, and of its end, to the offset in lit where the
+// byte is written, which may be the start of an escape.
+func literalOffsets(lit string) []int {
+	var offsets []int
+	if lit[0] == '`' {
+		for i := 1; i < len(lit)-1; i++ {
+			// Carriage returns are discarded from raw strings.
+			if lit[i] != '\r' {
+				offsets = append(offsets, i)
+			}
+		}
+		return append(offsets, len(lit)-1)
+	}
+	for rest := lit[1 : len(lit)-1]; len(rest) > 0; {
+		i := len(lit) - 1 - len(rest)
+		r, multibyte, tail, err := strconv.UnquoteChar(rest, '"')
+		if err != nil {
+			break
+		}
+		n := 1
+		if multibyte {
+			n = utf8.RuneLen(r)
+		}
+		for ; n > 0; n-- {
+			offsets = append(offsets, i)
+		}
+		rest = tail
+	}
+	return append(offsets, len(lit)-1)
+}
+
+// editLiteral makes the edits, in order of offset, to the string
+// literal lit whose offsets are given by literalOffsets. The names are
+// replaced where they are written, keeping the quotes and escapes of
+// lit, unless a name is itself written with escapes, in which case the
+// whole literal is quoted anew.
+func editLiteral(lit string, offsets []int, edits []literalEdit) string {
+	var buf strings.Builder
+	last := 0
+	for _, e := range edits {
+		start, end := offsets[e.offset], offsets[e.offset+len(e.name)]
+		if lit[start:end] != e.name {
+			return requoteLiteral(lit, edits)
+		}
+		buf.WriteString(lit[last:start])
+		buf.WriteString(e.to)
+		last = end
+	}
+	buf.WriteString(lit[last:])
+	return buf.String()
+}
+
+// requoteLiteral makes the edits to the content of the string literal
+// lit and quotes it again, with backquotes if lit had them and the new
+// content allows them.
+func requoteLiteral(lit string, edits []literalEdit) string {
+	s, _ := strconv.Unquote(lit)
+	var buf strings.Builder
+	last := 0
+	for _, e := range edits {
+		buf.WriteString(s[last:e.offset])
+		buf.WriteString(e.to)
+		last = e.offset + len(e.name)
+	}
+	buf.WriteString(s[last:])
+	t := buf.String()
+	if lit[0] == '`' && strconv.CanBackquote(t) {
+		return "`" + t + "`"
+	}
+	return strconv.Quote(t)
+}
+
+// replaceAll is like re.ReplaceAllStringFunc, but also passes the offset
+// of each match, plus base, to repl.
+func replaceAll(re *regexp.Regexp, s string, base int, repl func(match string, offset int) string) string {
+	var buf strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		buf.WriteString(s[last:loc[0]])
+		buf.WriteString(repl(s[loc[0]:loc[1]], base+loc[0]))
+		last = loc[1]
+	}
+	buf.WriteString(s[last:])
+	return buf.String()
+}
+
+// scanStrings finds the references to renamed names in the string
+// literals of the initial packages and in their template files, and
+// rewrites them if requested. It returns the references, the names of
+// the Go files with rewritten literals and the new contents of the
+// template files. It must be called before the syntax trees are
+// formatted.
+func (r *Renamer) scanStrings() ([]*StringRef, map[string]bool, map[string][]byte) {
+	all, members := r.stringTargets()
+	if len(all) == 0 {
+		return nil, nil, nil
+	}
+	goFiles := make(map[string]bool)
+
+	seen := make(map[token.Position]bool)
+	var refs []*StringRef
+	report := func(pos token.Position, kind, name, to string) bool {
+		rewrite := r.rewriteStrings && kind != "string" && to != ""
+		if !seen[pos] {
+			seen[pos] = true
+			refs = append(refs, &StringRef{Pos: pos, Kind: kind, Name: name, To: to, Rewritten: rewrite})
+		}
+		return rewrite
+	}
+
+	for _, pkg := range r.pkgs {
+		for _, f := range pkg.Syntax {
+			reflectArgs := make(map[*ast.BasicLit]bool)
+			ast.Inspect(f, func(node ast.Node) bool {
+				if call, ok := node.(*ast.CallExpr); ok && len(call.Args) == 1 {
+					if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+						fn, ok := pkg.TypesInfo.Uses[sel.Sel].(*types.Func)
+						if ok && fn.Pkg() != nil && fn.Pkg().Path() == "reflect" &&
+							(fn.Name() == "FieldByName" || fn.Name() == "MethodByName") {
+							if lit, ok := ast.Unparen(call.Args[0]).(*ast.BasicLit); ok {
+								reflectArgs[lit] = true
+							}
+						}
+					}
+				}
+				return true
+			})
+
+			tags := make(map[*ast.BasicLit]bool)
+			ast.Inspect(f, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.ImportSpec:
+					return false
+				case *ast.Field:
+					tags[n.Tag] = true
+				}
+				lit, ok := node.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING || tags[lit] {
+					return true
+				}
+				s, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					return true
+				}
+				offsets := literalOffsets(lit.Value)
+				pos := func(offset int) token.Position {
+					return r.position(lit.Pos() + token.Pos(offsets[offset]))
+				}
+
+				var edits []literalEdit
+				switch {
+				case reflectArgs[lit]:
+					to, ok := members[s]
+					if !ok || !report(pos(0), "reflect", s, to) {
+						return true
+					}
+					edits = append(edits, literalEdit{0, s, to})
+				case strings.Contains(s, "{{"):
+					rewriteTemplate(s, members, func(offset int, name, to string) bool {
+						if !report(pos(offset), "template", name, to) {
+							return false
+						}
+						edits = append(edits, literalEdit{offset, name, to})
+						return true
+					})
+				default:
+					for _, loc := range identRE.FindAllStringIndex(s, -1) {
+						name := s[loc[0]:loc[1]]
+						if to, ok := all[name]; ok {
+							report(pos(loc[0]), "string", name, to)
+						}
+					}
+					return true
+				}
+				if len(edits) > 0 {
+					lit.Value = editLiteral(lit.Value, offsets, edits)
+					goFiles[r.position(lit.Pos()).Filename] = true
+				}
+				return true
+			})
+		}
+	}
+
+	contents := make(map[string][]byte)
+	for _, filename := range r.templateFiles() {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		text := string(data)
+		t := rewriteTemplate(text, members, func(offset int, name, to string) bool {
+			line := 1 + strings.Count(text[:offset], "\n")
+			column := offset - strings.LastIndex(text[:offset], "\n")
+			pos := token.Position{Filename: filename, Offset: offset, Line: line, Column: column}
+			return report(pos, "template", name, to)
+		})
+		if t != text {
+			contents[filename] = []byte(t)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Pos.Filename != refs[j].Pos.Filename {
+			return refs[i].Pos.Filename < refs[j].Pos.Filename
+		}
+		return refs[i].Pos.Offset < refs[j].Pos.Offset
+	})
+	return refs, goFiles, contents
+}
+
+// templateFiles returns the template files in the directories of the
+// initial packages and the files they embed that look like templates.
+func (r *Renamer) templateFiles() []string {
+	isTemplate := func(filename string) bool {
+		ext := filepath.Ext(filename)
+		for _, e := range TemplateExts {
+			if ext == e {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := make(map[string]bool)
+	var filenames []string
+	add := func(filename string) {
+		if isTemplate(filename) && !seen[filename] {
+			seen[filename] = true
+			filenames = append(filenames, filename)
+		}
+	}
+	dirs := make(map[string]bool)
+	for _, pkg := range r.pkgs {
+		for _, filename := range pkg.GoFiles {
+			dirs[filepath.Dir(filename)] = true
+		}
+		for _, filename := range pkg.EmbedFiles {
+			add(filename)
+		}
+	}
+	for dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"))
+		for _, filename := range matches {
+			add(filename)
+		}
+	}
+	sort.Strings(filenames)
+	return filenames
+}
@@ -0,0 +1,88 @@
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestScanStrings(t *testing.T) {
+	fset, pkg := loadTestPackage(t, `package p
+
+import "reflect"
+
+type User struct {
+	User_Id int `+"`db:\"User_Id\"`"+`
+}
+
+func (u User) Get_Name() string { return "" }
+
+const Max_Len = 10
+
+func f(u User) {
+	_ = reflect.ValueOf(u).FieldByName("User_Id")
+	_ = reflect.ValueOf(u).MethodByName("Get_Name")
+	_ = `+"`{{.User_Id}} {{ $u.Get_Name }} .User_Id`"+`
+	_ = "Max_Len exceeded"
+	_ = "caf\u00e9\t{{.User_Id}}"
+	_ = `+"`{{.User_Id}}\n{{.Get_Name}}`"+`
+	_ = "{{.User\x5fId}}"
+}
+`)
+	r := New(fset, []*packages.Package{pkg})
+	r.SetRewriteStrings(true)
+	for _, name := range []string{"User_Id", "Get_Name", "Max_Len"} {
+		spec := lint.Check(&ast.Ident{Name: name})
+		r.Rename(lookupDef(pkg, name), *spec)
+	}
+
+	refs, goFiles, _ := r.scanStrings()
+	var actual []string
+	for _, ref := range refs {
+		actual = append(actual, fmt.Sprintf("%d:%d %s %s->%s %v", ref.Pos.Line, ref.Pos.Column, ref.Kind, ref.Name, ref.To, ref.Rewritten))
+	}
+	expected := []string{
+		"14:38 reflect User_Id->UserID true",
+		"15:39 reflect Get_Name->GetName true",
+		"16:10 template User_Id->UserID true",
+		"16:26 template Get_Name->GetName true",
+		"17:7 string Max_Len->MaxLen false",
+		"18:21 template User_Id->UserID true",
+		"19:10 template User_Id->UserID true",
+		"20:4 template Get_Name->GetName true",
+		"21:10 template User_Id->UserID true",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %q, got: %q", expected, actual)
+	}
+	if !goFiles["/src/p/p.go"] {
+		t.Errorf("expected p.go to be rewritten")
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`FieldByName("UserID")`,
+		`MethodByName("GetName")`,
+		"`{{.UserID}} {{ $u.GetName }} .User_Id`",
+		`"Max_Len exceeded"`,
+		`"caf\u00e9\t{{.UserID}}"`,
+		"`{{.UserID}}\n{{.GetName}}`",
+		`"{{.UserID}}"`,
+		"`db:\"User_Id\"`",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %s in:\n%s", s, buf.String())
+		}
+	}
+}
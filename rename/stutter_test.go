@@ -0,0 +1,66 @@
+package rename
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStutter(t *testing.T) {
+	testData := []struct {
+		name     string
+		user     string
+		expected map[string]string
+	}{
+		{
+			name: "importer",
+			user: `package user
+
+type UserService struct{}
+`,
+			expected: map[string]string{
+				"/src/example.com/user/user.go": `package user
+
+type Service struct{}
+`,
+				"/src/example.com/app/app.go": `package app
+
+import "example.com/user"
+
+var s user.Service
+`,
+			},
+		},
+		{
+			name: "conflict with the stripped name",
+			user: `package user
+
+type UserService struct{}
+
+func Service() {}
+`,
+			expected: map[string]string{},
+		},
+	}
+
+	app := `package app
+
+import "example.com/user"
+
+var s user.UserService
+`
+	for _, tt := range testData {
+		fset, pkgs := loadTestPackages(t, [][2]string{
+			{"example.com/user", tt.user},
+			{"example.com/app", app},
+		})
+		r := New(fset, pkgs)
+		renameChecked(r, pkgs[0])
+		actual, err := update(r)
+		if len(tt.expected) == 0 && err == nil {
+			t.Errorf("Test: %s, expected the rename to be refused", tt.name)
+		}
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("Test: %s, expected: %v, got: %v", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encodings is the list of encodings whose keys SetTags can preserve.
+var Encodings = []string{"json", "xml", "yaml"}
+
+// encodingPackages maps the path of a package of marshalling functions
+// to the struct tag key it uses.
+var encodingPackages = map[string]string{
+	"encoding/json":            "json",
+	"encoding/xml":             "xml",
+	"encoding/gob":             "gob",
+	"gopkg.in/yaml.v2":         "yaml",
+	"gopkg.in/yaml.v3":         "yaml",
+	"github.com/goccy/go-yaml": "yaml",
+	"sigs.k8s.io/yaml":         "json",
+}
+
+// SetTags makes Update add a struct tag for each of the given encodings
+// to a renamed field of a marshalled struct, so that its key stays the
+// same. Without one, Update only warns that the key changes.
+func (r *Renamer) SetTags(encodings []string) {
+	r.tags = make(map[string]bool)
+	for _, enc := range encodings {
+		r.tags[enc] = true
+	}
+}
+
+// defaultKey returns the key an encoding uses for a field without a tag.
+func defaultKey(enc, name string) string {
+	if enc == "yaml" {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// keylessOptions are the tag options with which a field is encoded
+// without a key, such as an inlined struct.
+var keylessOptions = map[string]bool{
+	"inline":   true,
+	"chardata": true,
+	"cdata":    true,
+	"innerxml": true,
+	"comment":  true,
+	"any":      true,
+}
+
+// keyed reports whether the tag value of an encoding, such as
+// "name,omitempty", keeps the key of a field when it is renamed: it
+// names the key, or the field is encoded without one. A value with
+// options only, such as ",omitempty", leaves the key to the field name.
+func keyed(value string) bool {
+	parts := strings.Split(value, ",")
+	if parts[0] != "" {
+		return true
+	}
+	for _, opt := range parts[1:] {
+		if keylessOptions[opt] {
+			return true
+		}
+	}
+	return false
+}
+
+// tagValue returns the offsets of the quoted value of key in tag, which
+// has the conventional format parsed by reflect.StructTag.Lookup.
+func tagValue(tag, key string) (start, end int, ok bool) {
+	i := 0
+	for i < len(tag) {
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		j := i
+		for j < len(tag) && tag[j] > ' ' && tag[j] != ':' && tag[j] != '"' && tag[j] != 0x7f {
+			j++
+		}
+		if j == i || j+1 >= len(tag) || tag[j] != ':' || tag[j+1] != '"' {
+			break
+		}
+		k := j + 2
+		for k < len(tag) && tag[k] != '"' {
+			if tag[k] == '\\' {
+				k++
+			}
+			k++
+		}
+		if k >= len(tag) {
+			break
+		}
+		if tag[i:j] == key {
+			return j + 1, k + 1, true
+		}
+		i = k + 1
+	}
+	return 0, 0, false
+}
+
+// structKey identifies a struct type across package variants by the
+// position of its first field.
+func (r *Renamer) structKey(st *types.Struct) (token.Position, bool) {
+	if st.NumFields() == 0 {
+		return token.Position{}, false
+	}
+	return r.position(st.Field(0).Pos()), true
+}
+
+// marshalled returns the encodings of the struct types that the initial
+// packages pass to marshalling functions, including the struct types
+// nested in them, by struct key.
+func (r *Renamer) marshalled() map[token.Position]map[string]bool {
+	result := make(map[token.Position]map[string]bool)
+	var mark func(t types.Type, enc string, seen map[types.Type]bool)
+	mark = func(t types.Type, enc string, seen map[types.Type]bool) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t := t.(type) {
+		case *types.Named:
+			mark(t.Underlying(), enc, seen)
+		case *types.Pointer:
+			mark(t.Elem(), enc, seen)
+		case *types.Slice:
+			mark(t.Elem(), enc, seen)
+		case *types.Array:
+			mark(t.Elem(), enc, seen)
+		case *types.Map:
+			mark(t.Elem(), enc, seen)
+		case *types.Struct:
+			if key, ok := r.structKey(t); ok {
+				if result[key] == nil {
+					result[key] = make(map[string]bool)
+				}
+				result[key][enc] = true
+			}
+			for i := 0; i < t.NumFields(); i++ {
+				mark(t.Field(i).Type(), enc, seen)
+			}
+		}
+	}
+
+	for _, pkg := range r.pkgs {
+		info := pkg.TypesInfo
+		for _, f := range pkg.Syntax {
+			ast.Inspect(f, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				var id *ast.Ident
+				switch fun := ast.Unparen(call.Fun).(type) {
+				case *ast.Ident:
+					id = fun
+				case *ast.SelectorExpr:
+					id = fun.Sel
+				default:
+					return true
+				}
+				fn, ok := info.Uses[id].(*types.Func)
+				if !ok || fn.Pkg() == nil || !isMarshalFunc(fn.Name()) {
+					return true
+				}
+				enc, ok := encodingPackages[fn.Pkg().Path()]
+				if !ok {
+					return true
+				}
+				for _, arg := range call.Args {
+					if t := info.TypeOf(arg); t != nil {
+						mark(t, enc, make(map[types.Type]bool))
+					}
+				}
+				return true
+			})
+		}
+	}
+	return result
+}
+
+func isMarshalFunc(name string) bool {
+	return strings.HasPrefix(name, "Marshal") || strings.HasPrefix(name, "Unmarshal") ||
+		name == "Encode" || name == "Decode"
+}
+
+// preserveKeys warns about, or adds tags for, the renamed fields whose
+// keys in a marshalled struct would change. It must be called before
+// the identifiers are renamed.
+func (r *Renamer) preserveKeys() {
+	var keys []objKey
+	for key := range r.objsToUpdate {
+		if objs := r.objectsOf(key); len(objs) > 0 {
+			if _, ok := objs[0].(*types.Var); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pos.Filename != keys[j].pos.Filename {
+			return keys[i].pos.Filename < keys[j].pos.Filename
+		}
+		return keys[i].pos.Offset < keys[j].pos.Offset
+	})
+	marshalled := r.marshalled()
+
+	for _, key := range keys {
+		for i, obj := range r.objectsOf(key) {
+			field, ok := obj.(*types.Var)
+			if !ok || !field.IsField() || !field.Exported() || field.Embedded() {
+				continue
+			}
+			pkg := r.packageOf(field)
+			if pkg == nil {
+				continue
+			}
+			st := r.structOf(field)
+			if st == nil {
+				continue
+			}
+			var tag reflect.StructTag
+			encs := make(map[string]bool)
+			for j := 0; j < st.NumFields(); j++ {
+				if st.Field(j) == field {
+					tag = reflect.StructTag(st.Tag(j))
+				}
+				for _, enc := range Encodings {
+					if _, ok := reflect.StructTag(st.Tag(j)).Lookup(enc); ok {
+						encs[enc] = true
+					}
+				}
+			}
+			if sk, ok := r.structKey(st); ok {
+				for enc := range marshalled[sk] {
+					encs[enc] = true
+				}
+			}
+
+			var names []string
+			for enc := range encs {
+				if v, ok := tag.Lookup(enc); !ok || !keyed(v) {
+					names = append(names, enc)
+				}
+			}
+			sort.Strings(names)
+			for _, enc := range names {
+				// Warn once, not once per package variant.
+				warn := func(format string, args ...interface{}) {
+					if i == 0 {
+						log.Printf("%s: "+format, append([]interface{}{key.pos}, args...)...)
+					}
+				}
+				switch {
+				case enc == "gob":
+					warn("renaming field %s changes its gob encoding", field.Name())
+				case !r.tags[enc]:
+					warn("renaming field %s changes its %s key; use -tags %s to keep it", field.Name(), enc, enc)
+				default:
+					if err := addTag(pkg.Syntax, field, enc, defaultKey(enc, field.Name())); err != nil {
+						warn("cannot keep the %s key of field %s: %v", enc, field.Name(), err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// addTag adds key:"value" to the tag of the field declared at the
+// position of obj, or prefixes value to the options in the tag for key.
+func addTag(files []*ast.File, obj *types.Var, key, value string) error {
+	var field *ast.Field
+	for _, f := range files {
+		if f.Pos() > obj.Pos() || obj.Pos() > f.End() {
+			continue
+		}
+		ast.Inspect(f, func(node ast.Node) bool {
+			if field != nil {
+				return false
+			}
+			if fd, ok := node.(*ast.Field); ok {
+				for _, id := range fd.Names {
+					if id.Pos() == obj.Pos() {
+						field = fd
+					}
+				}
+			}
+			return true
+		})
+	}
+	if field == nil {
+		return fmt.Errorf("declaration not found")
+	}
+	if len(field.Names) > 1 {
+		return fmt.Errorf("it is declared together with other fields")
+	}
+
+	tag := fmt.Sprintf("%s:%q", key, value)
+	if field.Tag != nil {
+		s, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return err
+		}
+		if v, ok := reflect.StructTag(s).Lookup(key); !ok {
+			tag = s + " " + tag
+		} else if keyed(v) {
+			// already added in another package variant
+			return nil
+		} else if start, end, ok := tagValue(s, key); ok {
+			tag = s[:start] + strconv.Quote(value+v) + s[end:]
+		} else {
+			return fmt.Errorf("malformed tag %s", field.Tag.Value)
+		}
+	}
+
+	lit := strconv.Quote(tag)
+	if strconv.CanBackquote(tag) {
+		lit = "`" + tag + "`"
+	}
+	if field.Tag != nil {
+		field.Tag.Value = lit
+	} else {
+		field.Tag = &ast.BasicLit{ValuePos: field.Type.End(), Kind: token.STRING, Value: lit}
+	}
+	return nil
+}
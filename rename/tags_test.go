@@ -0,0 +1,76 @@
+package rename
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+func TestPreserveKeys(t *testing.T) {
+	fset, pkg := loadTestPackage(t, `package p
+
+import "encoding/json"
+
+type Config struct {
+	User_Name string
+	Inner     []*Inner
+}
+
+type Inner struct {
+	Max_Len int `+"`db:\"max_len\"`"+`
+}
+
+type Tagged struct {
+	Host_Name string
+	Port      int `+"`yaml:\"port\"`"+`
+}
+
+type Plain struct {
+	Time_Out int
+}
+
+type Login struct {
+	Last_Login string `+"`json:\",omitempty\"`"+`
+}
+
+type Admin struct {
+	Is_Admin bool `+"`yaml:\",omitempty\" db:\"admin\"`"+`
+}
+
+type Wrapper struct {
+	Base_Info Plain `+"`yaml:\",inline\"`"+`
+}
+
+func save(c Config) ([]byte, error) { return json.Marshal(c) }
+`)
+	r := New(fset, []*packages.Package{pkg})
+	r.SetTags([]string{"json", "yaml"})
+	for _, name := range []string{"User_Name", "Max_Len", "Host_Name", "Time_Out", "Last_Login", "Is_Admin", "Base_Info"} {
+		spec := lint.Check(&ast.Ident{Name: name})
+		r.Rename(lookupDef(pkg, name), *spec)
+	}
+
+	contents, err := update(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := contents["/src/p/p.go"]
+
+	for _, expected := range []string{
+		"UserName string `json:\"User_Name\"`",
+		"MaxLen int `db:\"max_len\" json:\"Max_Len\"`",
+		"HostName string `yaml:\"host_name\"`",
+		"TimeOut int\n",
+		"LastLogin string `json:\"Last_Login,omitempty\"`",
+		"IsAdmin bool `yaml:\"is_admin,omitempty\" db:\"admin\"`",
+		"BaseInfo Plain `yaml:\",inline\"`",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %q in:\n%s", expected, content)
+		}
+	}
+}
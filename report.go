@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A finding is a name reported by -check. References counts the
+// identifiers that the rename would rewrite besides the declaration,
+// including those of the objects renamed together with it; Refused is
+// set if the rename would conflict, in which case none is rewritten.
+type finding struct {
+	Package    string `json:"package"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Name       string `json:"name"`
+	Thing      string `json:"thing"`
+	Category   string `json:"category"`
+	Suggestion string `json:"suggestion"`
+	Exported   bool   `json:"exported"`
+	References int    `json:"references"`
+	Refused    bool   `json:"refused"`
+
+	obj      types.Object
+	filename string
+	offset   int
+	def      site
+	sites    []site
+}
+
+// relPath returns filename relative to root in slash-separated form, or
+// filename itself if it lies outside root.
+func relPath(root, filename string) string {
+	if rel, err := filepath.Rel(root, filename); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	return filename
+}
+
+var formats = map[string]func(w io.Writer, findings []*finding) error{
+	"text":  writeText,
+	"json":  writeJSON,
+	"sarif": writeSARIF,
+}
+
+func writeText(w io.Writer, findings []*finding) error {
+	for _, f := range findings {
+		filename := path.Join(f.Package, filepath.Base(f.filename))
+		_, err := fmt.Fprintf(w, "%s:%d:%d: %s %s should be %s\n",
+			filename, f.Line, f.Column, f.Thing, f.Name, f.Suggestion)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSON writes one JSON object per line for each finding.
+func writeJSON(w io.Writer, findings []*finding) error {
+	enc := json.NewEncoder(w)
+	for _, f := range findings {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFindings(t *testing.T) {
+	findings := []*finding{
+		{
+			Package:    "example.com/p",
+			File:       "p/p.go",
+			Line:       3,
+			Column:     5,
+			Name:       "user_id",
+			Thing:      "var",
+			Category:   "underscore",
+			Suggestion: "userID",
+			References: 2,
+			filename:   "/src/p/p.go",
+		},
+	}
+
+	testData := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   "text",
+			expected: "example.com/p/p.go:3:5: var user_id should be userID\n",
+		},
+		{
+			format: "json",
+			expected: `{"package":"example.com/p","file":"p/p.go","line":3,"column":5,"name":"user_id",` +
+				`"thing":"var","category":"underscore","suggestion":"userID","exported":false,"references":2,"refused":false}` + "\n",
+		},
+	}
+
+	for _, tt := range testData {
+		var buf bytes.Buffer
+		if err := formats[tt.format](&buf, findings); err != nil {
+			t.Errorf("format: %s, unexpected error: %v", tt.format, err)
+			continue
+		}
+		if actual := buf.String(); tt.expected != actual {
+			t.Errorf("format: %s, expected: %q, got: %q", tt.format, tt.expected, actual)
+		}
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	findings := []*finding{
+		{
+			File:       "p/p.go",
+			Line:       3,
+			Column:     5,
+			Name:       "user_id",
+			Thing:      "var",
+			Category:   "underscore",
+			Suggestion: "userID",
+			def:        site{File: "p/p.go", Line: 3, Column: 5},
+			sites: []site{
+				{File: "p/p.go", Line: 3, Column: 5},
+				{File: "p/p.go", Line: 4, Column: 9},
+				{File: "q/q.go", Line: 7, Column: 2},
+			},
+		},
+		{
+			File:       "p/p.go",
+			Line:       5,
+			Column:     2,
+			Name:       "max_len",
+			Thing:      "var",
+			Category:   "underscore",
+			Suggestion: "maxLen",
+			def:        site{File: "p/p.go", Line: 5, Column: 2},
+			sites:      []site{{File: "p/p.go", Line: 5, Column: 2}},
+			Refused:    true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(log.Runs[0].Tool.Driver.Rules); n != len(sarifRules) {
+		t.Errorf("expected %d rules, got %d", len(sarifRules), n)
+	}
+	result := log.Runs[0].Results[0]
+	if result.RuleID != "underscore" {
+		t.Errorf("expected rule underscore, got %s", result.RuleID)
+	}
+	if region := result.Locations[0].PhysicalLocation.Region; region != (sarifRegion{3, 5, 12}) {
+		t.Errorf("unexpected region: %+v", region)
+	}
+	changes := result.Fixes[0].ArtifactChanges
+	if len(changes) != 2 || len(changes[0].Replacements) != 2 || len(changes[1].Replacements) != 1 {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+	if text := changes[1].Replacements[0].InsertedContent.Text; text != "userID" {
+		t.Errorf("expected replacement userID, got %s", text)
+	}
+	if fixes := log.Runs[0].Results[1].Fixes; len(fixes) != 0 {
+		t.Errorf("expected no fix for a refused rename, got: %+v", fixes)
+	}
+}
+
+func TestNewSite(t *testing.T) {
+	// The declaration of user_id follows a two-byte letter.
+	src := []byte("package p\n\nvar ã, user_id = 1, 2\n")
+	offset := bytes.Index(src, []byte("user_id"))
+	pos := token.Position{Filename: "/src/p/p.go", Offset: offset, Line: 3, Column: 9}
+
+	testData := []struct {
+		src      []byte
+		expected sarifRegion
+	}{
+		{src, sarifRegion{3, 8, 15}},
+		// Without the source, the byte column is kept.
+		{nil, sarifRegion{3, 9, 16}},
+	}
+
+	for _, tt := range testData {
+		if region := newSite("p/p.go", pos, tt.src).region("user_id"); region != tt.expected {
+			t.Errorf("expected region: %+v, got: %+v", tt.expected, region)
+		}
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	root := filepath.FromSlash("/src/m")
+	testData := []struct {
+		filename string
+		expected string
+	}{
+		{"/src/m/p/p.go", "p/p.go"},
+		{"/src/m/..p.go", "..p.go"},
+		// Outside root, the filename is kept.
+		{"/src/p.go", ""},
+		{"/src/m2/p.go", ""},
+	}
+
+	for _, tt := range testData {
+		filename := filepath.FromSlash(tt.filename)
+		expected := tt.expected
+		if expected == "" {
+			expected = filename
+		}
+		if actual := relPath(root, filename); actual != expected {
+			t.Errorf("filename: %s, expected: %s, got: %s", tt.filename, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/knzm/go-fixname/lint"
+)
+
+// Types for the subset of SARIF 2.1.0 written by -format sarif.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	Fixes     []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+const srcRoot = "%SRCROOT%"
+
+var sarifRules = []struct {
+	category    lint.Category
+	name        string
+	description string
+}{
+	{lint.AllCaps, "AllCapsName", "Names should use MixedCaps rather than ALL_CAPS."},
+	{lint.Underscore, "UnderscoreName", "Names should use MixedCaps rather than underscores."},
+	{lint.General, "InitialismName", "Initialisms in names should have a consistent case."},
+	{lint.Stutter, "StutteringName", "Exported names should not repeat the package name."},
+	{lint.Receiver, "ReceiverName", "The receivers of the methods of a type should be named alike, not this or self."},
+	{lint.ErrorName, "ErrorName", "Error variables should be named ErrFoo or errFoo, and error types FooError."},
+}
+
+// A site is a location of an identifier that a rename would rewrite.
+// Its column counts Unicode code points, as declared in the SARIF run.
+type site struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// newSite returns the site in file of the identifier at pos, counting
+// the code points before it on its line in src, the content of the
+// file. It keeps the byte column of pos if src does not cover it.
+func newSite(file string, pos token.Position, src []byte) site {
+	s := site{File: file, Line: pos.Line, Column: pos.Column}
+	if src != nil && pos.Offset <= len(src) {
+		start := bytes.LastIndexByte(src[:pos.Offset], '\n') + 1
+		s.Column = utf8.RuneCount(src[start:pos.Offset]) + 1
+	}
+	return s
+}
+
+func (s site) region(name string) sarifRegion {
+	return sarifRegion{
+		StartLine:   s.Line,
+		StartColumn: s.Column,
+		EndColumn:   s.Column + utf8.RuneCountInString(name),
+	}
+}
+
+func (s site) artifactLocation() sarifArtifactLocation {
+	return sarifArtifactLocation{URI: s.File, URIBaseID: srcRoot}
+}
+
+// writeSARIF writes the findings as a single SARIF log with one run.
+func writeSARIF(w io.Writer, findings []*finding) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "go-fixname",
+				InformationURI: "https://github.com/knzm/go-fixname",
+			},
+		},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	ruleIndex := make(map[string]int)
+	for i, rule := range sarifRules {
+		id := rule.category.String()
+		ruleIndex[id] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               id,
+			Name:             rule.name,
+			ShortDescription: sarifMessage{Text: rule.description},
+		})
+	}
+
+	for _, f := range findings {
+		def := f.def
+		result := sarifResult{
+			RuleID:    f.Category,
+			RuleIndex: ruleIndex[f.Category],
+			Level:     "warning",
+			Message:   sarifMessage{Text: fmt.Sprintf("%s %s should be %s", f.Thing, f.Name, f.Suggestion)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: def.artifactLocation(),
+					Region:           def.region(f.Name),
+				},
+			}},
+		}
+
+		// Group the replacements by file, as SARIF requires.
+		changes := make(map[string]*sarifArtifactChange)
+		var files []string
+		for _, s := range f.sites {
+			change := changes[s.File]
+			if change == nil {
+				change = &sarifArtifactChange{ArtifactLocation: s.artifactLocation()}
+				changes[s.File] = change
+				files = append(files, s.File)
+			}
+			change.Replacements = append(change.Replacements, sarifReplacement{
+				DeletedRegion:   s.region(f.Name),
+				InsertedContent: sarifMessage{Text: f.Suggestion},
+			})
+		}
+		// A refused rename has no fix, as applying it would break the
+		// program.
+		if len(files) > 0 && !f.Refused {
+			sort.Strings(files)
+			fix := sarifFix{Description: sarifMessage{Text: fmt.Sprintf("Rename %s to %s", f.Name, f.Suggestion)}}
+			for _, file := range files {
+				fix.ArtifactChanges = append(fix.ArtifactChanges, *changes[file])
+			}
+			result.Fixes = []sarifFix{fix}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
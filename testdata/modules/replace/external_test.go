@@ -0,0 +1,11 @@
+package replace_test
+
+import (
+	"testing"
+
+	"example.com/replace/sub"
+)
+
+func TestSub(t *testing.T) {
+	_ = sub.Value
+}
@@ -0,0 +1,5 @@
+package replace
+
+import "example.com/lib"
+
+var n = lib.Helper()
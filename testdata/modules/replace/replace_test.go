@@ -0,0 +1,7 @@
+package replace
+
+import "testing"
+
+func TestN(t *testing.T) {
+	_ = n
+}
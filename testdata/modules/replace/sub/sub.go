@@ -0,0 +1,3 @@
+package sub
+
+const Value = 1
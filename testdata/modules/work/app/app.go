@@ -0,0 +1,5 @@
+package app
+
+import "example.com/lib"
+
+var n = lib.Helper()